@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/user/isGPTReal/internal/api"
 	"github.com/user/isGPTReal/internal/detector"
+	"github.com/user/isGPTReal/internal/monitor"
+	"github.com/user/isGPTReal/internal/storage"
 )
 
 // 默认设置常量
@@ -24,8 +27,16 @@ func main() {
 	endpoint := flag.String("endpoint", "", "OpenAI兼容API端点 (可选，也可使用OPENAI_ENDPOINT环境变量)")
 	apiKey := flag.String("apikey", "", "API密钥 (可选，也可使用OPENAI_API_KEY环境变量)")
 	model := flag.String("model", DefaultModel, "要使用的模型名称")
+	encoding := flag.String("encoding", "", "本地计算token使用的编码（如o200k_base、cl100k_base），为空时根据模型自动选择")
 	interval := flag.Int("interval", DefaultInterval, "检测间隔（分钟），0表示不自动检测")
 	maxHistory := flag.Int("max-history", DefaultMaxHistory, "保存的历史记录最大数量")
+	maxAgeDays := flag.Int("max-age-days", 0, "历史记录的保留天数，0表示不按时间清理")
+	dataDir := flag.String("data-dir", "", "持久化保存检测结果的目录，为空时只保存在内存中")
+	genuineThreshold := flag.Float64("genuine-threshold", detector.DefaultGenuineThreshold, "评分不低于该值判定为真实API")
+	suspiciousThreshold := flag.Float64("suspicious-threshold", detector.DefaultSuspiciousThreshold, "评分不低于该值判定为可疑，否则判定为中转API")
+	minCoverage := flag.Float64("min-coverage", detector.DefaultMinCoverage, "有效检测项权重占比低于该值时结论为无法判断(0~1)")
+	concurrency := flag.Int("concurrency", detector.DefaultConcurrency, "同时运行的检测项数量上限")
+	checkTimeout := flag.Int("check-timeout", detector.DefaultCheckTimeout, "单个检测项的超时时间（秒）")
 
 	// 解析命令行参数
 	flag.Parse()
@@ -44,16 +55,64 @@ func main() {
 
 	// 创建检测器配置
 	config := detector.Config{
-		Endpoint:    apiEndpoint,
-		APIKey:      apiKeyValue,
-		Model:       *model,
-		Interval:    *interval,
-		MaxHistory:  *maxHistory,
-		SaveRawResp: true,
+		Endpoint:     apiEndpoint,
+		APIKey:       apiKeyValue,
+		Model:        *model,
+		Encoding:     *encoding,
+		Interval:     *interval,
+		MaxHistory:   *maxHistory,
+		MaxAgeDays:   *maxAgeDays,
+		SaveRawResp:  true,
+		Concurrency:  *concurrency,
+		CheckTimeout: *checkTimeout,
+		Thresholds: detector.Thresholds{
+			Genuine:     *genuineThreshold,
+			Suspicious:  *suspiciousThreshold,
+			MinCoverage: *minCoverage,
+		},
+	}
+
+	if err := config.Thresholds.Validate(); err != nil {
+		log.Fatalf("无效的判定阈值: %v", err)
+	}
+	if err := detector.ValidateEncoding(config.Encoding); err != nil {
+		log.Fatalf("无效的编码: %v", err)
+	}
+
+	// 创建监控器，默认监控目标来自上面的配置
+	m, err := monitor.New(config, storeFactory(*dataDir), targetsPath(*dataDir))
+	if err != nil {
+		log.Fatalf("初始化监控目标失败: %v", err)
 	}
 
 	// 创建并启动服务器
-	startServer(config, *port)
+	startServer(m, *port)
+}
+
+// storeFactory 根据数据目录返回各监控目标的结果存储，未指定目录时使用内存存储
+//
+// 默认目标的结果保存在<data-dir>/results.jsonl，其他目标保存在<data-dir>/targets/<id>.jsonl。
+func storeFactory(dataDir string) monitor.StoreFactory {
+	if dataDir != "" {
+		log.Printf("检测结果保存在: %s", dataDir)
+	}
+	return func(targetID string) (detector.Store, error) {
+		if dataDir == "" {
+			return detector.NewMemoryStore(), nil
+		}
+		if targetID == monitor.DefaultTargetID {
+			return storage.NewJSONLStore(filepath.Join(dataDir, "results.jsonl"))
+		}
+		return storage.NewJSONLStore(filepath.Join(dataDir, "targets", targetID+".jsonl"))
+	}
+}
+
+// targetsPath 返回保存监控目标的文件路径，未指定数据目录时不持久化
+func targetsPath(dataDir string) string {
+	if dataDir == "" {
+		return ""
+	}
+	return filepath.Join(dataDir, "targets.json")
 }
 
 // getEndpoint 获取API端点，优先使用命令行参数，然后是环境变量
@@ -73,9 +132,9 @@ func getAPIKey(cmdKey string) string {
 }
 
 // startServer 创建并启动API服务器
-func startServer(config detector.Config, port int) {
+func startServer(m *monitor.Monitor, port int) {
 	// 创建服务器实例
-	server := api.NewServer(config)
+	server := api.NewServer(m)
 
 	// 构建监听地址
 	addr := fmt.Sprintf(":%d", port)
@@ -1,43 +1,30 @@
 package api
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/robfig/cron/v3"
 	"github.com/user/isGPTReal/internal/detector"
+	"github.com/user/isGPTReal/internal/monitor"
 )
 
 // Server 表示API服务器
 type Server struct {
-	router              *gin.Engine        // HTTP路由器
-	detector            *detector.Detector // API检测器
-	cron                *cron.Cron         // 定时任务管理器
-	config              detector.Config    // 配置信息
-	cronID              cron.EntryID       // 当前运行的定时任务ID
-	mu                  sync.Mutex         // 保护并发访问定时任务
-	detectingInProgress bool               // 检测是否正在进行中
-	detectionStartTime  time.Time          // 检测开始时间
+	router  *gin.Engine      // HTTP路由器
+	monitor *monitor.Monitor // 管理所有监控目标的检测器和定时任务
 }
 
 // NewServer 创建一个新的API服务器
-func NewServer(config detector.Config) *Server {
+func NewServer(m *monitor.Monitor) *Server {
 	// 设置Gin模式为Release模式，减少不必要的日志输出
 	gin.SetMode(gin.ReleaseMode)
 
-	router := gin.Default()
-	detect := detector.NewDetector(config)
-
 	server := &Server{
-		router:              router,
-		detector:            detect,
-		cron:                cron.New(),
-		config:              config,
-		detectingInProgress: false,
+		router:  gin.Default(),
+		monitor: m,
 	}
 
 	// 设置API路由
@@ -54,21 +41,24 @@ func (s *Server) setupRoutes() {
 
 	// 首页路由
 	s.router.GET("/", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "index.html", gin.H{
-			"config": s.config,
-		})
+		c.HTML(http.StatusOK, "index.html", nil)
 	})
 
 	// API路由组
 	api := s.router.Group("/api")
 	{
-		// 配置相关API
+		// 配置相关API（共享检测参数和默认监控目标）
 		api.GET("/config", s.getConfig)
 		api.POST("/config", s.updateConfig)
 
-		// 检测结果相关API
+		// 检测结果相关API（默认监控目标）
 		api.GET("/results", s.getResults)
 		api.GET("/results/latest", s.getLatestResult)
+		api.GET("/results/:id", s.getResult)
+		api.GET("/results/:id/raw", s.getRawResponse)
+
+		// 检测项列表API
+		api.GET("/checks", s.getChecks)
 
 		// 检测控制API
 		api.POST("/detect", s.detectNow)
@@ -76,27 +66,40 @@ func (s *Server) setupRoutes() {
 		// 定时任务控制API
 		api.POST("/schedule/start", s.startSchedule)
 		api.POST("/schedule/stop", s.stopSchedule)
+
+		// 监控目标API
+		api.GET("/targets", s.listTargets)
+		api.POST("/targets", s.createTarget)
+		api.GET("/targets/:id", s.getTarget)
+		api.PUT("/targets/:id", s.updateTarget)
+		api.DELETE("/targets/:id", s.deleteTarget)
+		api.POST("/targets/:id/detect", s.detectTarget)
+		api.GET("/targets/:id/results", s.getTargetResults)
+		api.GET("/targets/:id/matrix", s.getTargetMatrix)
 	}
 }
 
 // Run 启动API服务器
 func (s *Server) Run(addr string) error {
-	// 启动定时任务管理器
-	s.cron.Start()
-
-	// 如果配置了定时检测间隔，则启动定时任务
-	if s.config.Interval > 0 {
-		s.startScheduleWithInterval(s.config.Interval)
-	}
+	// 启动各监控目标的定时任务
+	s.monitor.Start()
 
 	// 启动HTTP服务器
 	return s.router.Run(addr)
 }
 
-// getConfig 返回当前配置
+// defaultDetector 返回默认监控目标的检测器
+func (s *Server) defaultDetector() *detector.Detector {
+	return s.monitor.Detector(monitor.DefaultTargetID)
+}
+
+// getConfig 返回当前配置（API密钥已隐去）
 func (s *Server) getConfig(c *gin.Context) {
-	// 直接返回配置信息
-	c.JSON(http.StatusOK, s.config)
+	config := s.defaultDetector().Config()
+	if config.APIKey != "" {
+		config.APIKey = detector.RedactKey(config.APIKey)
+	}
+	c.JSON(http.StatusOK, config)
 }
 
 // updateConfig 更新配置
@@ -106,48 +109,65 @@ func (s *Server) updateConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的配置参数: " + err.Error()})
 		return
 	}
+	if err := newConfig.Thresholds.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的判定阈值: " + err.Error()})
+		return
+	}
+	if err := detector.ValidateEncoding(newConfig.Encoding); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的编码: " + err.Error()})
+		return
+	}
 
-	// 保存原有的定时检测间隔
-	oldInterval := s.config.Interval
-
-	// 更新检测器配置
-	s.detector.UpdateConfig(newConfig)
+	// 更新共享参数和默认目标，检测间隔变化时会自动调整定时任务
+	s.monitor.UpdateBase(newConfig)
 
-	// 更新本地配置副本
-	s.config = newConfig
+	c.JSON(http.StatusOK, gin.H{"message": "配置已更新"})
+}
 
-	// 如果定时检测间隔有变化，调整定时任务
-	if oldInterval != newConfig.Interval {
-		// 停止现有定时任务
-		s.stopScheduleInternal()
+// getResults 返回默认目标的所有检测结果（不含原始请求记录）
+func (s *Server) getResults(c *gin.Context) {
+	c.JSON(http.StatusOK, resultSummaries(s.defaultDetector().GetResults()))
+}
 
-		// 如果新的间隔大于0，启动新的定时任务
-		if newConfig.Interval > 0 {
-			s.startScheduleWithInterval(newConfig.Interval)
-		}
+// getResult 按ID返回完整的检测结果
+func (s *Server) getResult(c *gin.Context) {
+	result := s.monitor.FindResult(c.Param("id"))
+	if result == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "检测结果不存在"})
+		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "配置已更新"})
+	c.JSON(http.StatusOK, result)
 }
 
-// getResults 返回所有检测结果
-func (s *Server) getResults(c *gin.Context) {
-	c.JSON(http.StatusOK, s.detector.GetResults())
+// getRawResponse 返回检测结果的原始请求记录，可通过check参数只返回某个检测项
+func (s *Server) getRawResponse(c *gin.Context) {
+	result := s.monitor.FindResult(c.Param("id"))
+	if result == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "检测结果不存在"})
+		return
+	}
+
+	name := c.Query("check")
+	if name == "" {
+		c.JSON(http.StatusOK, result.RawResponse)
+		return
+	}
+	exchanges, ok := result.RawResponse[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该检测项没有原始请求记录"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{name: exchanges})
 }
 
-// getLatestResult 返回最新的检测结果
+// getLatestResult 返回默认目标最新的检测结果
 func (s *Server) getLatestResult(c *gin.Context) {
-	// 检查是否有检测正在进行中
-	s.mu.Lock()
-	isDetecting := s.detectingInProgress
-	startTime := s.detectionStartTime
-	s.mu.Unlock()
-
-	// 获取最新结果
-	result := s.detector.GetLatestResult()
+	status, _ := s.monitor.Status(monitor.DefaultTargetID)
+	result := status.Latest
 
-	// 如果检测正在进行中，且结果是在检测开始前的，返回检测中状态
-	if isDetecting && (result == nil || result.Timestamp.Before(startTime)) {
+	// 检测正在进行中时返回检测中状态；多模型检测时前几个模型的结果已保存，
+	// 但整轮检测尚未完成
+	if status.Detecting {
 		c.JSON(http.StatusOK, gin.H{
 			"status":    "detecting",
 			"message":   "检测正在进行中",
@@ -162,32 +182,21 @@ func (s *Server) getLatestResult(c *gin.Context) {
 		return
 	}
 
-	// 返回检测结果
+	// 返回检测结果（原始请求记录通过/results/:id/raw获取）
 	c.JSON(http.StatusOK, result)
 }
 
-// detectNow 执行一次立即检测
+// getChecks 返回所有已注册的检测项
+func (s *Server) getChecks(c *gin.Context) {
+	c.JSON(http.StatusOK, detector.ListChecks())
+}
+
+// detectNow 对默认目标执行一次立即检测
 func (s *Server) detectNow(c *gin.Context) {
-	// 设置检测状态为进行中
-	s.mu.Lock()
-	s.detectingInProgress = true
-	s.detectionStartTime = time.Now()
-	s.mu.Unlock()
-
-	// 异步执行检测，避免阻塞HTTP请求
-	go func() {
-		s.detector.DetectOnce()
-
-		// 检测完成，更新状态
-		s.mu.Lock()
-		s.detectingInProgress = false
-		s.mu.Unlock()
-	}()
-
-	c.JSON(http.StatusOK, gin.H{"message": "检测已启动"})
+	s.startDetection(c, monitor.DefaultTargetID)
 }
 
-// startSchedule 启动定时检测
+// startSchedule 启动默认目标的定时检测
 func (s *Server) startSchedule(c *gin.Context) {
 	// 从请求参数中获取间隔时间
 	type ScheduleRequest struct {
@@ -200,103 +209,143 @@ func (s *Server) startSchedule(c *gin.Context) {
 		return
 	}
 
-	// 加锁保护并发访问
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if err := s.monitor.SetInterval(monitor.DefaultTargetID, req.Interval); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "启动定时任务失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("定时检测已启动，间隔为%d分钟", req.Interval)})
+}
 
-	// 停止现有的定时任务
-	if s.cronID != 0 {
-		s.cron.Remove(s.cronID)
-		s.cronID = 0
+// stopSchedule 停止默认目标的定时检测
+func (s *Server) stopSchedule(c *gin.Context) {
+	if err := s.monitor.SetInterval(monitor.DefaultTargetID, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "停止定时任务失败: " + err.Error()})
+		return
 	}
 
-	// 更新配置
-	s.config.Interval = req.Interval
-	s.detector.UpdateConfig(s.config)
+	c.JSON(http.StatusOK, gin.H{"message": "定时检测已停止"})
+}
 
-	// 启动新的定时任务
-	schedule := fmt.Sprintf("@every %dm", req.Interval)
-	id, err := s.cron.AddFunc(schedule, func() {
-		s.detector.DetectOnce()
-	})
+// listTargets 返回所有监控目标及其最新结果
+func (s *Server) listTargets(c *gin.Context) {
+	statuses := s.monitor.Statuses()
+	for i := range statuses {
+		statuses[i].Target = statuses[i].Target.Redacted()
+	}
+	c.JSON(http.StatusOK, statuses)
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "启动定时任务失败: " + err.Error()})
+// createTarget 添加监控目标
+func (s *Server) createTarget(c *gin.Context) {
+	var target monitor.Target
+	if err := c.ShouldBindJSON(&target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的监控目标: " + err.Error()})
 		return
 	}
 
-	s.cronID = id
-	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("定时检测已启动，间隔为%d分钟", req.Interval)})
+	created, err := s.monitor.Add(target)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, created.Redacted())
 }
 
-// startScheduleWithInterval 使用指定间隔启动定时检测
-func (s *Server) startScheduleWithInterval(minutes int) {
-	// 加锁保护并发访问
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// 停止现有的定时任务
-	if s.cronID != 0 {
-		s.cron.Remove(s.cronID)
-		s.cronID = 0
+// getTarget 返回监控目标及其最新结果
+func (s *Server) getTarget(c *gin.Context) {
+	status, ok := s.monitor.Status(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": monitor.ErrNotFound.Error()})
+		return
 	}
+	status.Target = status.Target.Redacted()
+	c.JSON(http.StatusOK, status)
+}
 
-	// 启动新的定时任务
-	schedule := fmt.Sprintf("@every %dm", minutes)
-	id, err := s.cron.AddFunc(schedule, func() {
-		log.Printf("执行定时检测任务，间隔%d分钟", minutes)
-
-		// 设置检测状态为进行中
-		s.mu.Lock()
-		s.detectingInProgress = true
-		s.detectionStartTime = time.Now()
-		s.mu.Unlock()
-
-		// 执行检测并等待结果
-		result := s.detector.DetectOnce()
-
-		// 检测完成，更新状态
-		s.mu.Lock()
-		s.detectingInProgress = false
-		s.mu.Unlock()
-
-		// 记录检测结果
-		if result.IsRealAPI {
-			log.Printf("定时检测完成: 真实API")
-		} else {
-			log.Printf("定时检测完成: 中转API")
-		}
-	})
+// updateTarget 更新监控目标，api_key为空时保留原密钥
+func (s *Server) updateTarget(c *gin.Context) {
+	var target monitor.Target
+	if err := c.ShouldBindJSON(&target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的监控目标: " + err.Error()})
+		return
+	}
 
+	updated, err := s.monitor.Update(c.Param("id"), target)
 	if err != nil {
-		log.Printf("启动定时任务失败: %v", err)
+		c.JSON(targetErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, updated.Redacted())
+}
 
-	s.cronID = id
-	log.Printf("定时检测已启动，间隔为%d分钟", minutes)
+// deleteTarget 删除监控目标
+func (s *Server) deleteTarget(c *gin.Context) {
+	if err := s.monitor.Remove(c.Param("id")); err != nil {
+		c.JSON(targetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "监控目标已删除"})
 }
 
-// stopSchedule 停止定时检测
-func (s *Server) stopSchedule(c *gin.Context) {
-	s.stopScheduleInternal()
+// detectTarget 对指定目标执行一次立即检测
+func (s *Server) detectTarget(c *gin.Context) {
+	s.startDetection(c, c.Param("id"))
+}
 
-	// 更新配置
-	s.config.Interval = 0
-	s.detector.UpdateConfig(s.config)
+// getTargetResults 返回指定目标的所有检测结果（不含原始请求记录）
+func (s *Server) getTargetResults(c *gin.Context) {
+	d := s.monitor.Detector(c.Param("id"))
+	if d == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": monitor.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resultSummaries(d.GetResults()))
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "定时检测已停止"})
+// getTargetMatrix 返回指定目标每个模型的最新检测结果
+func (s *Server) getTargetMatrix(c *gin.Context) {
+	matrix, err := s.monitor.Matrix(c.Param("id"))
+	if err != nil {
+		c.JSON(targetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if matrix == nil {
+		matrix = []detector.Result{}
+	}
+	c.JSON(http.StatusOK, matrix)
 }
 
-// stopScheduleInternal 内部使用的停止定时任务的函数
-func (s *Server) stopScheduleInternal() {
-	// 加锁保护并发访问
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// startDetection 在后台启动检测，避免阻塞HTTP请求
+func (s *Server) startDetection(c *gin.Context, id string) {
+	err := s.monitor.DetectAsync(id)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "检测已启动"})
+	case errors.Is(err, monitor.ErrDetecting):
+		// 已有检测在进行，调用方继续轮询结果即可
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+	default:
+		c.JSON(targetErrorStatus(err), gin.H{"error": err.Error()})
+	}
+}
+
+// targetErrorStatus 返回监控目标操作错误对应的HTTP状态码
+func targetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, monitor.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, monitor.ErrDefaultTarget):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
 
-	// 如果有运行中的定时任务，停止它
-	if s.cronID != 0 {
-		s.cron.Remove(s.cronID)
-		s.cronID = 0
+// resultSummaries 去掉检测结果中的原始请求记录，减小列表接口的响应体积
+func resultSummaries(results []detector.Result) []detector.Result {
+	for i := range results {
+		results[i] = results[i].WithoutRawResponse()
 	}
+	return results
 }
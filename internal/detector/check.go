@@ -0,0 +1,212 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check 表示一项可插拔的检测
+//
+// 新的检测项只需实现该接口并在init中调用Register注册，
+// 无需修改Result、DetectOnce、API处理函数或前端页面。
+type Check interface {
+	Name() string        // 检测项的唯一名称，例如"logprobs"
+	Description() string // 检测项的简要说明，用于界面展示
+	Weight() int         // 检测项的权重（对应README中的星级）
+	// Run 执行检测；d是本次检测的配置快照，可直接读取d.config而无需加锁
+	Run(ctx context.Context, d *Detector) Outcome
+}
+
+// TimeoutCheck 是可选接口，依次发送多个请求的检测项实现后可延长超时时间
+//
+// base为配置中单个检测项的超时时间，通常按请求数返回其倍数。
+type TimeoutCheck interface {
+	Check
+	Timeout(base time.Duration) time.Duration
+}
+
+// CheckStatus 表示单项检测的状态
+type CheckStatus string
+
+const (
+	StatusPass    CheckStatus = "pass"    // 检测通过
+	StatusFail    CheckStatus = "fail"    // 检测未通过
+	StatusSkip    CheckStatus = "skip"    // 检测被跳过（例如模型不支持）
+	StatusError   CheckStatus = "error"   // 检测过程出错，无法判断
+	StatusTimeout CheckStatus = "timeout" // 检测未在截止时间内完成
+)
+
+// Outcome 表示一项检测运行后的结构化结果
+type Outcome struct {
+	Status   CheckStatus            `json:"status"`
+	Message  string                 `json:"message,omitempty"`  // 一句话结论
+	Evidence []string               `json:"evidence,omitempty"` // 逐条的判断依据
+	Data     map[string]interface{} `json:"data,omitempty"`     // 检测项自定义的结构化数据
+}
+
+// CheckResult 表示某一检测项在一次检测中的结果
+type CheckResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Weight      int    `json:"weight"`
+	Outcome
+	DurationMs int64 `json:"duration_ms"` // 检测耗时（毫秒）
+}
+
+// expect 记录一条断言，断言失败时将检测标记为未通过
+func (o *Outcome) expect(ok bool, format string, args ...interface{}) bool {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+		o.Status = StatusFail
+	}
+	o.Evidence = append(o.Evidence, mark+" "+fmt.Sprintf(format, args...))
+	return ok
+}
+
+// note 记录一条不影响结论的说明
+func (o *Outcome) note(format string, args ...interface{}) {
+	o.Evidence = append(o.Evidence, "· "+fmt.Sprintf(format, args...))
+}
+
+// set 记录一项结构化数据
+func (o *Outcome) set(key string, value interface{}) {
+	if o.Data == nil {
+		o.Data = make(map[string]interface{})
+	}
+	o.Data[key] = value
+}
+
+// conclude 根据已记录的断言确定最终状态
+func (o Outcome) conclude(passMsg, failMsg string) Outcome {
+	if o.Status == "" {
+		o.Status = StatusPass
+	}
+	if o.Message == "" {
+		if o.Status == StatusPass {
+			o.Message = passMsg
+		} else {
+			o.Message = failMsg
+		}
+	}
+	return o
+}
+
+// deviations 按类别记录逐项检查中发现的偏差，每类只展示前几条证据
+type deviations struct {
+	out    *Outcome
+	limit  int
+	counts map[string]int
+}
+
+// newDeviations 创建一个偏差记录器，每类最多展示limit条证据
+func newDeviations(out *Outcome, limit int) *deviations {
+	return &deviations{out: out, limit: limit, counts: make(map[string]int)}
+}
+
+// add 记录一条偏差，并将检测标记为未通过
+func (d *deviations) add(kind, format string, args ...interface{}) {
+	d.counts[kind]++
+	if d.counts[kind] <= d.limit {
+		d.out.expect(false, format, args...)
+	} else {
+		d.out.Status = StatusFail
+	}
+}
+
+// passIfNone 某类没有偏差时记录一条通过的证据
+func (d *deviations) passIfNone(kind, format string, args ...interface{}) {
+	if d.counts[kind] == 0 {
+		d.out.expect(true, format, args...)
+	}
+}
+
+// summarize 对超出展示条数的类别补充说明总数
+func (d *deviations) summarize(kinds ...string) {
+	for _, kind := range kinds {
+		if n := d.counts[kind]; n > d.limit {
+			d.out.note("%s类偏差共%d处，仅列出前%d处", kind, n, d.limit)
+		}
+	}
+}
+
+// errorOutcome 构造一个出错的检测结果
+//
+// API因请求参数拒绝请求（400/422）时记为未通过而不是出错：出错的检测项不参与评分，
+// 否则中转只需拒绝难以伪造的参数（logprobs、tools等）就能避开这些检测。
+func errorOutcome(err error) Outcome {
+	var se *statusError
+	if errors.As(err, &se) && se.rejected() {
+		return Outcome{Status: StatusFail, Message: "API拒绝了请求: " + err.Error()}
+	}
+	return Outcome{Status: StatusError, Message: err.Error()}
+}
+
+// skipOutcome 构造一个被跳过的检测结果
+func skipOutcome(format string, args ...interface{}) Outcome {
+	return Outcome{Status: StatusSkip, Message: fmt.Sprintf(format, args...)}
+}
+
+// 全局检测项注册表
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Check)
+)
+
+// Register 注册一个检测项，名称重复时会panic
+func Register(c Check) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if c == nil {
+		panic("detector: Register的检测项为nil")
+	}
+	name := c.Name()
+	if _, dup := registry[name]; dup {
+		panic("detector: 重复注册检测项 " + name)
+	}
+	registry[name] = c
+}
+
+// Checks 返回所有已注册的检测项，按名称排序
+func Checks() []Check {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	checks := make([]Check, 0, len(registry))
+	for _, c := range registry {
+		checks = append(checks, c)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name() < checks[j].Name() })
+	return checks
+}
+
+// LookupCheck 按名称查找已注册的检测项
+func LookupCheck(name string) (Check, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	c, ok := registry[name]
+	return c, ok
+}
+
+// CheckInfo 描述一个已注册的检测项，供API和界面使用
+type CheckInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Weight      int    `json:"weight"`
+}
+
+// ListChecks 返回所有已注册检测项的描述信息
+func ListChecks() []CheckInfo {
+	checks := Checks()
+	infos := make([]CheckInfo, 0, len(checks))
+	for _, c := range checks {
+		infos = append(infos, CheckInfo{Name: c.Name(), Description: c.Description(), Weight: c.Weight()})
+	}
+	return infos
+}
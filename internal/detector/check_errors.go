@@ -0,0 +1,140 @@
+package detector
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(errorsCheck{})
+}
+
+// errorsCheck 检查错误响应是否符合官方API的格式
+//
+// 故意发送几种非法请求，比较HTTP状态码和error对象中的type、code、param。
+// 基于one-api/new-api的中转和网页版反向代理有各自特征明显的错误格式，
+// 例如one-api会在message末尾追加"(request id: ...)"。
+type errorsCheck struct{}
+
+func (errorsCheck) Name() string { return "errors" }
+func (errorsCheck) Description() string {
+	return "发送非法请求，检测错误响应的状态码和error对象格式是否与官方API一致"
+}
+func (errorsCheck) Weight() int { return 3 }
+
+// Timeout 每个错误用例依次发送，各计一份超时时间
+func (errorsCheck) Timeout(base time.Duration) time.Duration {
+	return base * time.Duration(len(errorCases))
+}
+
+// errorCase 一种非法请求及官方API的预期错误
+type errorCase struct {
+	name   string
+	mutate func(req map[string]interface{})
+	status int
+	param  interface{} // 预期的error.param，nil表示应为null
+	code   string      // 预期的error.code，为空时不检查
+}
+
+var errorCases = []errorCase{
+	{
+		name:   "不存在的模型",
+		mutate: func(req map[string]interface{}) { req["model"] = "gpt-nonexistent-model-xyz" },
+		status: http.StatusNotFound,
+		param:  nil,
+		code:   "model_not_found",
+	},
+	{
+		name:   "temperature=5",
+		mutate: func(req map[string]interface{}) { req["temperature"] = 5 },
+		status: http.StatusBadRequest,
+		param:  "temperature",
+	},
+	{
+		name:   "messages格式错误",
+		mutate: func(req map[string]interface{}) { req["messages"] = "hello" },
+		status: http.StatusBadRequest,
+		param:  "messages",
+	},
+	{
+		name:   "max_tokens过大",
+		mutate: func(req map[string]interface{}) { req["max_tokens"] = 1000000 },
+		status: http.StatusBadRequest,
+		param:  "max_tokens",
+	},
+}
+
+// relayErrorTypes 中转软件特有的error.type
+var relayErrorTypes = []string{"one_api_error", "new_api_error", "shell_api_error", "upstream_error"}
+
+func (errorsCheck) Run(ctx context.Context, d *Detector) Outcome {
+	var out Outcome
+	statuses := make(map[string]int, len(errorCases))
+
+	for _, tc := range errorCases {
+		req := map[string]interface{}{
+			"model":      d.config.Model,
+			"messages":   []map[string]string{{"role": "user", "content": "Say hi."}},
+			"max_tokens": 5,
+		}
+		tc.mutate(req)
+
+		resp, err := d.sendRequest(ctx, req)
+		if err != nil {
+			return errorOutcome(err)
+		}
+		statuses[tc.name] = resp.StatusCode
+		log.Printf("Errors检测: %s, 状态码=%d", tc.name, resp.StatusCode)
+
+		out.expect(resp.StatusCode == tc.status, "%s: 状态码为%d（实际为%d）", tc.name, tc.status, resp.StatusCode)
+		errObj, ok := resp.errorObject()
+		if !ok {
+			out.expect(false, "%s: 响应体不是包含error对象的JSON: %s", tc.name, previewString(string(resp.Body), 80))
+			continue
+		}
+
+		// error对象应包含message、type、param、code四个字段
+		message := stringField(errObj, "message")
+		_, hasParam := errObj["param"]
+		_, hasCode := errObj["code"]
+		out.expect(message != "" && stringField(errObj, "type") != "" && hasParam && hasCode,
+			"%s: error对象包含message、type、param和code字段", tc.name)
+		out.expect(stringField(errObj, "type") == "invalid_request_error", "%s: error.type为invalid_request_error（实际为%q）", tc.name, stringField(errObj, "type"))
+		// 推理模型的max_tokens会改为max_completion_tokens发送
+		param := tc.param
+		if param == "max_tokens" {
+			param = tokenLimitParam(d.config.Model)
+		}
+		out.expect(errObj["param"] == param, "%s: error.param为%v（实际为%v）", tc.name, describeParam(param), describeParam(errObj["param"]))
+		if tc.code != "" {
+			out.expect(errObj["code"] == tc.code, "%s: error.code为%s（实际为%v）", tc.name, tc.code, errObj["code"])
+		}
+
+		// 中转软件的特征
+		if strings.Contains(message, "(request id:") {
+			out.expect(false, "%s: error.message带有one-api/new-api的request id后缀", tc.name)
+		}
+		for _, t := range relayErrorTypes {
+			if stringField(errObj, "type") == t {
+				out.expect(false, "%s: error.type为中转软件特有的%s", tc.name, t)
+			}
+		}
+	}
+	out.set("statuses", statuses)
+
+	return out.conclude("错误响应与官方API格式一致", "错误响应与官方API格式不一致")
+}
+
+// describeParam 以JSON的方式描述error.param，区分null和字符串
+func describeParam(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return jsonType(v)
+}
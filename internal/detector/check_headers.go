@@ -0,0 +1,174 @@
+package detector
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Register(headersCheck{})
+}
+
+// headersCheck 根据响应头判断上游来源
+//
+// 官方API和Azure OpenAI会返回各自特有的响应头（如openai-processing-ms、
+// apim-request-id），而one-api、LiteLLM等中转软件通常会丢弃这些头或添加
+// 自己的头。响应头快照会记录在检测结果中。
+type headersCheck struct{}
+
+func (headersCheck) Name() string { return "headers" }
+func (headersCheck) Description() string {
+	return "根据响应头识别上游来源（OpenAI、Azure、中转软件或未知）"
+}
+func (headersCheck) Weight() int { return 2 }
+
+// 上游类型
+const (
+	UpstreamOpenAI  = "openai"
+	UpstreamAzure   = "azure"
+	UpstreamRelay   = "relay"
+	UpstreamUnknown = "unknown"
+)
+
+// headerMarker 表示一个可用于识别上游的响应头特征
+type headerMarker struct {
+	header   string // 响应头名称（小写），以"-"结尾时按前缀匹配
+	upstream string
+	label    string
+}
+
+var headerMarkers = []headerMarker{
+	{"openai-version", UpstreamOpenAI, "OpenAI"},
+	{"openai-processing-ms", UpstreamOpenAI, "OpenAI"},
+	{"openai-organization", UpstreamOpenAI, "OpenAI"},
+	{"openai-project", UpstreamOpenAI, "OpenAI"},
+	{"apim-request-id", UpstreamAzure, "Azure API Management"},
+	{"x-ms-region", UpstreamAzure, "Azure"},
+	{"x-ms-rai-invoked", UpstreamAzure, "Azure内容过滤"},
+	{"x-ms-deployment-name", UpstreamAzure, "Azure"},
+	{"azureml-model-session", UpstreamAzure, "Azure ML"},
+	{"x-oneapi-request-id", UpstreamRelay, "one-api/new-api"},
+	{"x-new-api-version", UpstreamRelay, "new-api"},
+	{"x-litellm-", UpstreamRelay, "LiteLLM"},
+	{"helicone-", UpstreamRelay, "Helicone"},
+	{"x-portkey-", UpstreamRelay, "Portkey"},
+	{"x-kong-", UpstreamRelay, "Kong网关"},
+}
+
+// snapshotHeaders 快照中记录的响应头，以"-"结尾时按前缀匹配
+var snapshotHeaders = []string{
+	"x-request-id", "openai-", "x-ratelimit-", "cf-ray", "cf-cache-status", "server",
+	"via", "x-powered-by", "apim-request-id", "x-ms-", "azureml-",
+	"x-oneapi-request-id", "x-new-api-", "x-litellm-", "helicone-", "x-portkey-", "x-kong-",
+}
+
+// openaiRequestID 官方API的x-request-id格式
+var openaiRequestID = regexp.MustCompile(`^req_[0-9a-f]{32}$`)
+
+func (headersCheck) Run(ctx context.Context, d *Detector) Outcome {
+	req := map[string]interface{}{
+		"model":      d.config.Model,
+		"messages":   []map[string]string{{"role": "user", "content": "Say hi."}},
+		"max_tokens": 5,
+	}
+	resp, err := d.sendRequest(ctx, req)
+	if err != nil {
+		return errorOutcome(err)
+	}
+	var response map[string]interface{}
+	if err := resp.decode(&response); err != nil {
+		return errorOutcome(err)
+	}
+
+	var out Outcome
+	snapshot := headerSnapshot(resp.Header)
+	out.set("headers", snapshot)
+
+	// 按特征统计各类上游的命中情况
+	found := make(map[string][]string)
+	for _, m := range headerMarkers {
+		for _, name := range matchingHeaders(snapshot, m.header) {
+			found[m.upstream] = append(found[m.upstream], name)
+			out.note("响应头%s表明上游为%s", name, m.label)
+		}
+	}
+	if server := snapshot["server"]; server != "" {
+		out.note("server: %s", server)
+	}
+	if id := snapshot["x-request-id"]; id != "" && found[UpstreamOpenAI] != nil {
+		out.expect(openaiRequestID.MatchString(id), "x-request-id符合官方格式req_<32位十六进制>: %s", id)
+	}
+
+	upstream := classifyUpstream(found)
+	out.set("upstream", upstream)
+
+	log.Printf("Headers检测: 上游=%s, 记录响应头%d个", upstream, len(snapshot))
+
+	out.expect(len(found[UpstreamRelay]) == 0, "未发现中转软件特有的响应头")
+	out.expect(len(found[UpstreamOpenAI]) > 0 || len(found[UpstreamAzure]) > 0, "包含OpenAI或Azure特有的响应头")
+
+	failMsg := "上游识别为" + upstreamLabel(upstream)
+	if upstream == UpstreamOpenAI || upstream == UpstreamAzure {
+		failMsg = "响应头与" + upstreamLabel(upstream) + "的格式不一致"
+	}
+	return out.conclude("响应头来自"+upstreamLabel(upstream), failMsg)
+}
+
+// headerSnapshot 提取用于识别上游的响应头，名称统一为小写
+func headerSnapshot(header http.Header) map[string]string {
+	snapshot := make(map[string]string)
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		for _, h := range snapshotHeaders {
+			if lower == h || (strings.HasSuffix(h, "-") && strings.HasPrefix(lower, h)) {
+				snapshot[lower] = strings.Join(values, ", ")
+				break
+			}
+		}
+	}
+	return snapshot
+}
+
+// matchingHeaders 返回快照中与特征匹配的响应头名称（已排序）
+func matchingHeaders(snapshot map[string]string, header string) []string {
+	var names []string
+	for name := range snapshot {
+		if name == header || (strings.HasSuffix(header, "-") && strings.HasPrefix(name, header)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// classifyUpstream 根据命中的特征判断上游类型，中转特征优先
+func classifyUpstream(found map[string][]string) string {
+	switch {
+	case len(found[UpstreamRelay]) > 0:
+		return UpstreamRelay
+	case len(found[UpstreamAzure]) > 0:
+		return UpstreamAzure
+	case len(found[UpstreamOpenAI]) > 0:
+		return UpstreamOpenAI
+	default:
+		return UpstreamUnknown
+	}
+}
+
+// upstreamLabel 返回上游类型的中文描述
+func upstreamLabel(upstream string) string {
+	switch upstream {
+	case UpstreamOpenAI:
+		return "OpenAI官方"
+	case UpstreamAzure:
+		return "Azure OpenAI"
+	case UpstreamRelay:
+		return "中转软件"
+	default:
+		return "未知"
+	}
+}
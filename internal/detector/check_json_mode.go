@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(jsonModeCheck{})
+}
+
+// jsonModeCheck 检查JSON模式(response_format: json_object)
+//
+// 除了多次采样确认输出总是JSON对象外，还会验证提示词中未提及JSON时
+// 官方API返回的400错误，包装网页版ChatGPT的中转几乎不会复现这一行为。
+type jsonModeCheck struct{}
+
+func (jsonModeCheck) Name() string { return "json_mode" }
+func (jsonModeCheck) Description() string {
+	return "检测JSON模式(json_object)的输出是否总是JSON对象，以及提示词未提及JSON时是否返回官方的400错误"
+}
+func (jsonModeCheck) Weight() int { return 2 }
+
+// Timeout 每次采样和缺少JSON提示词的请求依次发送，各计一份超时时间
+func (jsonModeCheck) Timeout(base time.Duration) time.Duration { return base * (jsonModeSamples + 1) }
+
+// jsonModeSamples JSON模式的采样次数
+const jsonModeSamples = 3
+
+func (jsonModeCheck) Run(ctx context.Context, d *Detector) Outcome {
+	var out Outcome
+
+	// 多次采样，每次输出都应能解析为JSON对象
+	objects := 0
+	for i := 0; i < jsonModeSamples; i++ {
+		req := map[string]interface{}{
+			"model":           d.config.Model,
+			"messages":        []map[string]string{{"role": "user", "content": "Describe a random fruit as a JSON object with the keys name, color and taste."}},
+			"response_format": map[string]interface{}{"type": "json_object"},
+			"temperature":     1.0,
+			"max_tokens":      100,
+		}
+
+		var response map[string]interface{}
+		if err := d.makeRequest(ctx, req, &response); err != nil {
+			return errorOutcome(err)
+		}
+		choice, _ := firstChoice(response)
+		content, _ := messageContent(choice)
+
+		var obj map[string]interface{}
+		if out.expect(json.Unmarshal([]byte(content), &obj) == nil, "第%d次采样的输出是JSON对象: %s", i+1, previewString(content, 60)) {
+			objects++
+		}
+	}
+	out.set("json_objects", fmt.Sprintf("%d/%d", objects, jsonModeSamples))
+
+	// 提示词未提及JSON时，官方API会拒绝json_object模式的请求
+	req := map[string]interface{}{
+		"model":           d.config.Model,
+		"messages":        []map[string]string{{"role": "user", "content": "Describe a random fruit in one sentence."}},
+		"response_format": map[string]interface{}{"type": "json_object"},
+		"max_tokens":      50,
+	}
+	resp, err := d.sendRequest(ctx, req)
+	if err != nil {
+		return errorOutcome(err)
+	}
+	out.set("error_status", resp.StatusCode)
+
+	log.Printf("JSONMode检测: JSON对象=%d/%d, 未提及JSON时状态码=%d", objects, jsonModeSamples, resp.StatusCode)
+
+	if !out.expect(resp.StatusCode == http.StatusBadRequest, "提示词未提及JSON时返回400（实际为%d）", resp.StatusCode) {
+		return out.conclude("", "JSON模式与官方API行为不一致")
+	}
+	errObj, ok := resp.errorObject()
+	if !out.expect(ok, "错误响应包含error对象") {
+		return out.conclude("", "JSON模式与官方API行为不一致")
+	}
+	message := stringField(errObj, "message")
+	out.expect(strings.Contains(strings.ToLower(message), "json"), "error.message提及json: %s", previewString(message, 100))
+	out.expect(stringField(errObj, "type") == "invalid_request_error", "error.type为invalid_request_error（实际为%q）", stringField(errObj, "type"))
+	out.expect(stringField(errObj, "param") == "messages", "error.param为messages（实际为%v）", errObj["param"])
+
+	return out.conclude("JSON模式与官方API行为一致", "JSON模式与官方API行为不一致")
+}
@@ -0,0 +1,179 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(logitBiasCheck{})
+}
+
+// logitBiasCheck 检查logit_bias参数是否在token层面生效
+//
+// 用本地tokenizer计算token ID：对显而易见的答案"Paris"施加-100使其无法生成，
+// 对一个无意义的单token词施加+100使模型只能输出它。基于提示词伪造的中转
+// 无法得知token ID对应的内容，也就无法模拟这种token级别的控制。
+type logitBiasCheck struct{}
+
+func (logitBiasCheck) Name() string { return "logit_bias" }
+func (logitBiasCheck) Description() string {
+	return "检测logit_bias能否按本地tokenizer计算的token ID屏蔽或强制生成指定token"
+}
+func (logitBiasCheck) Weight() int { return 3 }
+
+// Timeout 抑制和强制两个请求依次发送
+func (logitBiasCheck) Timeout(base time.Duration) time.Duration { return 2 * base }
+
+var (
+	// bannedWords 屏蔽的答案及其常见变体，每个变体需为单个token
+	bannedWords = []string{"Paris", " Paris"}
+	// boostCandidates 强制生成的候选词，使用第一个编码为单个token的词
+	boostCandidates = []string{" banana", " zebra", " giraffe"}
+)
+
+func (logitBiasCheck) Run(ctx context.Context, d *Detector) Outcome {
+	if out, skip := skipForReasoning(d, "logit_bias和logprobs"); skip {
+		return out
+	}
+
+	var out Outcome
+	out.set("encoding", string(d.encoding()))
+
+	// 屏蔽答案：将"Paris"各变体的token ID设为-100
+	banned := make(map[string]interface{})
+	bannedTokens := make(map[string]bool)
+	for _, word := range bannedWords {
+		ids, err := d.encode(word)
+		if err != nil {
+			return errorOutcome(err)
+		}
+		if len(ids) != 1 {
+			out.note("%q被切分为%d个token，不参与屏蔽", word, len(ids))
+			continue
+		}
+		banned[strconv.FormatUint(uint64(ids[0]), 10)] = -100
+		bannedTokens[word] = true
+	}
+	if len(banned) == 0 {
+		return skipOutcome("当前编码%s下没有可屏蔽的单token答案", d.encoding())
+	}
+	out.set("banned_token_ids", banned)
+
+	req := map[string]interface{}{
+		"model":       d.config.Model,
+		"messages":    []map[string]string{{"role": "user", "content": "What is the capital of France? Answer with the city name only."}},
+		"logit_bias":  banned,
+		"logprobs":    true,
+		"temperature": 0,
+		"max_tokens":  10,
+	}
+	var response map[string]interface{}
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+	choice, ok := firstChoice(response)
+	if !ok {
+		return errorOutcome(fmt.Errorf("无法解析响应格式"))
+	}
+	bannedOutput, _ := messageContent(choice)
+	out.set("banned_output", bannedOutput)
+
+	// 优先按logprobs中的token判断；没有logprobs时只能按文本判断
+	if tokens, ok := logprobsTokens(choice); ok {
+		generated := false
+		for _, token := range tokens {
+			if bannedTokens[token] {
+				generated = true
+				break
+			}
+		}
+		out.expect(!generated, "被屏蔽的token未出现在生成结果中: %q", previewString(bannedOutput, 60))
+	} else {
+		out.note("响应未包含logprobs，按文本判断")
+		out.expect(!strings.Contains(bannedOutput, "Paris"), "回复中不包含被屏蔽的Paris: %q", previewString(bannedOutput, 60))
+	}
+
+	// 强制生成：将一个无意义词的token ID设为+100
+	word, id, err := d.singleTokenWord(boostCandidates)
+	if err != nil {
+		return errorOutcome(err)
+	}
+	if word == "" {
+		out.note("当前编码下候选词均不是单个token，跳过强制生成测试")
+		return out.conclude("logit_bias屏蔽生效", "logit_bias未生效")
+	}
+	out.set("boosted_token", word)
+	out.set("boosted_token_id", id)
+
+	req = map[string]interface{}{
+		"model":       d.config.Model,
+		"messages":    []map[string]string{{"role": "user", "content": "Say hello."}},
+		"logit_bias":  map[string]interface{}{strconv.FormatUint(uint64(id), 10): 100},
+		"temperature": 0,
+		"max_tokens":  5,
+	}
+	response = nil
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+	choice, ok = firstChoice(response)
+	if !ok {
+		return errorOutcome(fmt.Errorf("无法解析响应格式"))
+	}
+	content, _ := messageContent(choice)
+	out.set("boosted_output", content)
+
+	// 输出应只由被强制的词重复组成
+	target := strings.TrimSpace(word)
+	fields := strings.Fields(content)
+	only := len(fields) > 0
+	for _, f := range fields {
+		if f != target {
+			only = false
+			break
+		}
+	}
+	out.expect(only, "输出只包含被强制的token%q: %q", word, previewString(content, 60))
+
+	log.Printf("LogitBias检测: 屏蔽后输出=%q, 强制后输出=%q", previewString(bannedOutput, 30), previewString(content, 30))
+
+	return out.conclude("logit_bias在token层面生效", "logit_bias未生效或未按token ID生效")
+}
+
+// singleTokenWord 返回候选词中第一个编码为单个token的词及其token ID
+func (d *Detector) singleTokenWord(candidates []string) (string, uint, error) {
+	for _, word := range candidates {
+		ids, err := d.encode(word)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(ids) == 1 {
+			return word, ids[0], nil
+		}
+	}
+	return "", 0, nil
+}
+
+// logprobsTokens 返回choice的logprobs中逐个生成的token
+func logprobsTokens(choice map[string]interface{}) ([]string, bool) {
+	logprobs, ok := choice["logprobs"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	entries, ok := logprobs["content"].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	tokens := make([]string, 0, len(entries))
+	for _, item := range entries {
+		entry, _ := item.(map[string]interface{})
+		token, _ := entry["token"].(string)
+		tokens = append(tokens, token)
+	}
+	return tokens, true
+}
@@ -0,0 +1,182 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"unicode/utf8"
+)
+
+func init() {
+	Register(logprobsCheck{})
+}
+
+// logprobsCheck 检查logprobs参数是否生效且返回的概率信息真实有效
+//
+// 仅判断logprobs字段是否存在是不够的，任何中转都可以返回一个空对象。
+// 这里逐个校验token：能否用模型的编码重新切分为单个token、bytes是否与
+// token一致、top_logprobs的数量和排序，以及所选token是否位于候选之首。
+type logprobsCheck struct{}
+
+func (logprobsCheck) Name() string { return "logprobs" }
+func (logprobsCheck) Description() string {
+	return "检测API返回的token概率信息是否完整且真实有效"
+}
+func (logprobsCheck) Weight() int { return 4 }
+
+// 请求的候选token数量和逐项检查的证据条数上限
+const (
+	requestedTopLogprobs = 5
+	logprobsEvidenceCap  = 3
+	logprobEpsilon       = 1e-4
+)
+
+func (logprobsCheck) Run(ctx context.Context, d *Detector) Outcome {
+	if out, skip := skipForReasoning(d, "logprobs"); skip {
+		return out
+	}
+
+	// 构造请求，要求返回logprobs；温度为0时所选token应是概率最高的候选
+	req := map[string]interface{}{
+		"model":        d.config.Model,
+		"messages":     []map[string]string{{"role": "user", "content": "What is the capital of France? Answer in one short sentence."}},
+		"logprobs":     true,
+		"top_logprobs": requestedTopLogprobs,
+		"temperature":  0,
+		"max_tokens":   30,
+	}
+
+	var response map[string]interface{}
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+
+	choice, ok := firstChoice(response)
+	if !ok {
+		return errorOutcome(fmt.Errorf("无法解析响应格式"))
+	}
+
+	var out Outcome
+
+	// 检查响应中是否包含logprobs
+	logprobs, hasLogprobs := choice["logprobs"].(map[string]interface{})
+
+	// 简洁的日志输出
+	log.Printf("Logprobs检测: 请求logprobs=true, 响应包含logprobs=%v", hasLogprobs)
+
+	if !out.expect(hasLogprobs, "响应包含logprobs对象") {
+		return out.conclude("", "未返回logprobs信息")
+	}
+	entries, ok := logprobs["content"].([]interface{})
+	if !out.expect(ok && len(entries) > 0, "logprobs.content为非空数组") {
+		return out.conclude("", "logprobs信息为空")
+	}
+
+	// token数量应与usage一致，拼接后应与回复内容一致
+	completion := usageInt(response, "completion_tokens")
+	out.expect(len(entries) == completion, "logprobs.content包含%d个token，usage.completion_tokens为%d", len(entries), completion)
+	out.set("tokens", len(entries))
+
+	enc, err := d.codec()
+	if err != nil {
+		return errorOutcome(err)
+	}
+
+	devs := newDeviations(&out, logprobsEvidenceCap)
+	var joined []byte
+	for i, item := range entries {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			devs.add("shape", "第%d项不是对象", i+1)
+			continue
+		}
+		token, _ := entry["token"].(string)
+		logprob, hasLogprob := entry["logprob"].(float64)
+		raw, hasBytes := byteArray(entry["bytes"])
+		joined = append(joined, raw...)
+
+		// bytes与token一致；不完整的UTF-8字节（例如被切开的汉字）无法还原为token字符串
+		if !hasBytes {
+			devs.add("bytes", "第%d个token(%q)缺少bytes数组", i+1, token)
+		} else if utf8.Valid(raw) {
+			if !bytes.Equal(raw, []byte(token)) {
+				devs.add("bytes", "第%d个token(%q)的bytes与token的UTF-8编码不一致", i+1, token)
+			}
+			// 官方返回的每个token用模型的编码应恰好切分为一个token
+			if ids, _, err := enc.Encode(token); err == nil && len(ids) != 1 {
+				devs.add("retokenize", "第%d个token(%q)被%s切分为%d个token", i+1, token, enc.GetName(), len(ids))
+			}
+		}
+
+		if !hasLogprob || logprob > 0 {
+			devs.add("logprob", "第%d个token(%q)的logprob(%v)不是≤0的数值", i+1, token, entry["logprob"])
+		}
+
+		// 候选token的数量、取值和排序
+		tops, _ := entry["top_logprobs"].([]interface{})
+		if len(tops) != requestedTopLogprobs {
+			devs.add("top_count", "第%d个token的top_logprobs有%d项，请求了%d项", i+1, len(tops), requestedTopLogprobs)
+		}
+		found, sorted, valid := false, true, true
+		best := 0.0
+		prev := 0.0
+		for j, t := range tops {
+			top, _ := t.(map[string]interface{})
+			lp, ok := top["logprob"].(float64)
+			if !ok || lp > 0 {
+				valid = false
+				continue
+			}
+			if j == 0 {
+				best = lp
+			} else if lp > prev+logprobEpsilon {
+				sorted = false
+			}
+			prev = lp
+			if top["token"] == token {
+				found = true
+			}
+		}
+		if !valid {
+			devs.add("top_value", "第%d个token的top_logprobs包含非法的logprob", i+1)
+		}
+		if !sorted {
+			devs.add("top_order", "第%d个token的top_logprobs未按logprob降序排列", i+1)
+		}
+		if len(tops) > 0 && hasLogprob && !found && logprob < best-logprobEpsilon {
+			devs.add("chosen", "第%d个token(%q)的logprob(%.4f)低于最佳候选(%.4f)且不在候选中", i+1, token, logprob, best)
+		}
+	}
+
+	devs.passIfNone("bytes", "每个token的bytes与token的UTF-8编码一致")
+	devs.passIfNone("retokenize", "每个token用%s重新编码后均为单个token", enc.GetName())
+	devs.passIfNone("logprob", "每个token的logprob均≤0")
+	devs.passIfNone("top_count", "每个token都返回了%d个top_logprobs", requestedTopLogprobs)
+	devs.passIfNone("top_order", "top_logprobs均按logprob降序排列")
+	devs.passIfNone("chosen", "所选token均位于候选之中或高于所有候选")
+	devs.summarize("shape", "bytes", "retokenize", "logprob", "top_count", "top_value", "top_order", "chosen")
+
+	if content, ok := messageContent(choice); ok {
+		out.expect(string(joined) == content, "各token的bytes拼接后与回复内容一致")
+	}
+
+	return out.conclude("logprobs信息完整且有效", "logprobs信息缺失或不可信")
+}
+
+// byteArray 将JSON中的整数数组转换为字节切片
+func byteArray(v interface{}) ([]byte, bool) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	b := make([]byte, 0, len(arr))
+	for _, item := range arr {
+		n, ok := item.(float64)
+		if !ok || n < 0 || n > 255 {
+			return nil, false
+		}
+		b = append(b, byte(n))
+	}
+	return b, true
+}
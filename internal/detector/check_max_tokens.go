@@ -0,0 +1,85 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+func init() {
+	Register(maxTokensCheck{})
+}
+
+// maxTokensCheck 检查max_tokens参数是否生效
+//
+// 只比较usage中的completion_tokens无法发现伪造用量的中转，因此还要求
+// finish_reason为length，且返回内容用本地编码重新切分后恰好为max_tokens个token。
+// 推理模型不接受max_tokens，此时直接使用max_completion_tokens且不预留推理token。
+type maxTokensCheck struct{}
+
+func (maxTokensCheck) Name() string { return "max_tokens" }
+func (maxTokensCheck) Description() string {
+	return "检测API是否在max_tokens处截断输出，且截断后的内容恰好为max_tokens个token"
+}
+func (maxTokensCheck) Weight() int { return 3 }
+
+func (maxTokensCheck) Run(ctx context.Context, d *Detector) Outcome {
+	// 构造请求，限制最大token数，并让模型输出远超该限制的内容
+	maxTokens := 16
+	param := tokenLimitParam(d.config.Model)
+	req := map[string]interface{}{
+		"model":    d.config.Model,
+		"messages": []map[string]string{{"role": "user", "content": "Write a long essay about the history of artificial intelligence."}},
+		param:      maxTokens,
+	}
+
+	var out Outcome
+	out.set("encoding", string(d.encoding()))
+	out.set("token_param", param)
+
+	var response map[string]interface{}
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+
+	// 获取API返回的token数量
+	apiTokenCount := usageInt(response, "completion_tokens")
+	apiTotalTokens := usageInt(response, "total_tokens")
+	reasoning := reasoningTokens(response)
+	out.set("api_token_count", apiTokenCount)
+	out.set("api_total_tokens", apiTotalTokens)
+
+	choice, ok := firstChoice(response)
+	if !ok {
+		return errorOutcome(fmt.Errorf("无法解析响应格式"))
+	}
+	returnedContent, _ := messageContent(choice)
+
+	// 计算返回内容的本地token数
+	localTokenCount, err := d.countTokens(returnedContent)
+	if err != nil {
+		return errorOutcome(err)
+	}
+	out.set("local_token_count", localTokenCount)
+
+	// 简洁的日志输出
+	log.Printf("MaxTokens检测: 目标限制=%d(%s), 本地计算=%d, API返回=%d, 总tokens=%d",
+		maxTokens, param, localTokenCount, apiTokenCount, apiTotalTokens)
+	log.Printf("MaxTokens检测: 返回内容=%q", returnedContent)
+
+	out.expect(apiTokenCount >= 0, "响应包含usage.completion_tokens")
+	out.expect(apiTokenCount <= maxTokens, "completion_tokens=%d 不超过 %s=%d", apiTokenCount, param, maxTokens)
+
+	finishReason := stringField(choice, "finish_reason")
+	out.expect(finishReason == "length", "finish_reason为length（实际为%q）", finishReason)
+
+	// 推理token不可见，只有非推理输出才能与本地计算精确比较
+	if reasoning > 0 {
+		out.note("completion_tokens中包含%d个推理token，跳过精确计数比较", reasoning)
+	} else {
+		out.expect(localTokenCount == maxTokens, "返回内容用%s重新编码为%d个token，应恰好为%d", d.encoding(), localTokenCount, maxTokens)
+		out.expect(apiTokenCount == localTokenCount, "completion_tokens(%d)与本地计算(%d)一致", apiTokenCount, localTokenCount)
+	}
+
+	return out.conclude("max_tokens限制生效，输出被精确截断", "max_tokens限制未生效或截断不正确")
+}
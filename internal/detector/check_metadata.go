@@ -0,0 +1,102 @@
+package detector
+
+import (
+	"context"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(metadataCheck{})
+}
+
+// metadataCheck 检查响应元数据（id、object、created、model）是否合理
+//
+// 官方API返回的model是所请求模型解析后的快照版本，例如请求gpt-4o-mini时
+// 返回gpt-4o-mini-2024-07-18。中转用其他模型冒充时，model字段常会暴露出
+// 不同的模型系列。
+type metadataCheck struct{}
+
+func (metadataCheck) Name() string { return "metadata" }
+func (metadataCheck) Description() string {
+	return "检测响应的id、object、created和model字段是否与官方API一致，识别模型替换"
+}
+func (metadataCheck) Weight() int { return 2 }
+
+// maxClockSkew created与本地时间允许的最大偏差
+const maxClockSkew = 5 * time.Minute
+
+var (
+	// completionIDPattern 官方chat.completion的id格式
+	completionIDPattern = regexp.MustCompile(`^chatcmpl-[A-Za-z0-9]{20,}$`)
+	// snapshotSuffix 模型快照版本的日期后缀，如2024-07-18或旧模型的0613
+	snapshotSuffix = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}|\d{4})$`)
+)
+
+func (metadataCheck) Run(ctx context.Context, d *Detector) Outcome {
+	req := map[string]interface{}{
+		"model":      d.config.Model,
+		"messages":   []map[string]string{{"role": "user", "content": "Say hi."}},
+		"max_tokens": 5,
+	}
+
+	start := time.Now()
+	var response map[string]interface{}
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+	end := time.Now()
+
+	var out Outcome
+
+	id := stringField(response, "id")
+	out.set("id", id)
+	out.expect(completionIDPattern.MatchString(id), "id以chatcmpl-开头且长度合理: %q", id)
+
+	object := stringField(response, "object")
+	out.expect(object == "chat.completion", "object为chat.completion（实际为%q）", object)
+
+	// created应在请求前后的时间窗口内
+	created, ok := response["created"].(float64)
+	if out.expect(ok, "响应包含created时间戳") {
+		t := time.Unix(int64(created), 0)
+		skew := 0.0
+		if t.Before(start) {
+			skew = start.Sub(t).Seconds()
+		} else if t.After(end) {
+			skew = t.Sub(end).Seconds()
+		}
+		out.set("created_skew_seconds", math.Round(skew))
+		out.expect(skew <= maxClockSkew.Seconds(), "created与本地时间相差%.0f秒（允许%.0f秒）", skew, maxClockSkew.Seconds())
+	}
+
+	// model应为所请求的模型或其快照版本
+	requested := normalizeModelName(d.config.Model)
+	model := stringField(response, "model")
+	out.set("requested_model", d.config.Model)
+	out.set("model", model)
+	resolved := isModelSnapshot(requested, model)
+	out.expect(resolved, "model(%q)为所请求模型%q或其快照版本", model, requested)
+
+	log.Printf("Metadata检测: id=%s, object=%s, 请求模型=%s, 返回模型=%s", id, object, d.config.Model, model)
+
+	if !resolved && model != "" {
+		return out.conclude("", "返回的模型与请求不一致，疑似模型替换")
+	}
+	return out.conclude("响应元数据与官方API一致", "响应元数据与官方API不一致")
+}
+
+// isModelSnapshot 判断返回的模型名是否为请求模型本身或带日期后缀的快照版本
+//
+// 例如gpt-4o-mini对应gpt-4o-mini-2024-07-18，gpt-4对应gpt-4-0613。
+func isModelSnapshot(requested, returned string) bool {
+	returned = normalizeModelName(returned)
+	if returned == requested {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(returned, requested+"-")
+	return ok && snapshotSuffix.MatchString(suffix)
+}
@@ -0,0 +1,29 @@
+package detector
+
+import "testing"
+
+func TestIsModelSnapshot(t *testing.T) {
+	tests := []struct {
+		requested, returned string
+		want                bool
+	}{
+		{"gpt-4o-mini", "gpt-4o-mini", true},
+		{"gpt-4o-mini", "gpt-4o-mini-2024-07-18", true},
+		{"gpt-4", "gpt-4-0613", true},
+		{"gpt-4o", "GPT-4o-2024-08-06", true},
+		{"gpt-4o", "openai/gpt-4o", true},
+		{"gpt-4o", "gpt-4o-mini", false},
+		{"gpt-4o", "gpt-4o-mini-2024-07-18", false},
+		{"gpt-4", "gpt-4-turbo", false},
+		{"gpt-4", "gpt-4-06130", false},
+		{"gpt-4", "gpt-4-2024-7-18", false},
+		{"gpt-4o", "gpt-4o-2024-08-06-extra", false},
+		{"gpt-4o", "gpt-3.5-turbo", false},
+	}
+
+	for _, tt := range tests {
+		if got := isModelSnapshot(tt.requested, tt.returned); got != tt.want {
+			t.Errorf("isModelSnapshot(%q, %q) = %v, want %v", tt.requested, tt.returned, got, tt.want)
+		}
+	}
+}
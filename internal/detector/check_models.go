@@ -0,0 +1,204 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(modelsCheck{})
+}
+
+// modelsCheck 检查/v1/models端点返回的模型列表
+//
+// 官方API的模型列表是object为list的对象，每个模型都有id、object、created和
+// owned_by字段，且只包含官方发布的模型。中转常常不实现该端点、省略字段，或
+// 在列表中混入其他厂商的模型。不在内置目录中的模型可能是新发布的官方模型，
+// 只作为参考记录，不判定为未通过。
+type modelsCheck struct{}
+
+func (modelsCheck) Name() string { return "models" }
+func (modelsCheck) Description() string {
+	return "检测/v1/models返回的模型列表格式是否与官方API一致，且不包含其他厂商的模型"
+}
+func (modelsCheck) Weight() int { return 2 }
+
+// modelsEvidenceCap 限制每类偏差展示的条数
+const modelsEvidenceCap = 5
+
+// officialModels 官方API提供的模型（不含日期后缀的快照版本）
+//
+// 带-YYYY-MM-DD或-MMDD后缀的快照按基础模型识别；目录之外的模型只记录不扣分，
+// 因此官方发布新模型后目录暂未更新也不会导致误判。
+var officialModels = map[string]bool{
+	"gpt-3.5-turbo": true, "gpt-3.5-turbo-0125": true, "gpt-3.5-turbo-1106": true,
+	"gpt-3.5-turbo-16k": true, "gpt-3.5-turbo-instruct": true, "gpt-3.5-turbo-instruct-0914": true,
+	"gpt-4": true, "gpt-4-0613": true, "gpt-4-0125-preview": true, "gpt-4-1106-preview": true,
+	"gpt-4-turbo": true, "gpt-4-turbo-preview": true,
+	"gpt-4o": true, "gpt-4o-mini": true, "chatgpt-4o-latest": true,
+	"gpt-4o-audio-preview": true, "gpt-4o-mini-audio-preview": true,
+	"gpt-4o-realtime-preview": true, "gpt-4o-mini-realtime-preview": true,
+	"gpt-4o-search-preview": true, "gpt-4o-mini-search-preview": true,
+	"gpt-4o-transcribe": true, "gpt-4o-mini-transcribe": true, "gpt-4o-mini-tts": true,
+	"gpt-4.1": true, "gpt-4.1-mini": true, "gpt-4.1-nano": true, "gpt-4.5-preview": true,
+	"gpt-5": true, "gpt-5-mini": true, "gpt-5-nano": true, "gpt-5-chat-latest": true,
+	"gpt-audio": true, "gpt-realtime": true, "gpt-image-1": true,
+	"o1": true, "o1-mini": true, "o1-preview": true, "o1-pro": true,
+	"o3": true, "o3-mini": true, "o3-pro": true, "o3-deep-research": true,
+	"o4-mini": true, "o4-mini-deep-research": true, "codex-mini-latest": true, "computer-use-preview": true,
+	"dall-e-2": true, "dall-e-3": true,
+	"tts-1": true, "tts-1-hd": true, "tts-1-1106": true, "tts-1-hd-1106": true, "whisper-1": true,
+	"text-embedding-3-small": true, "text-embedding-3-large": true, "text-embedding-ada-002": true,
+	"omni-moderation": true, "omni-moderation-latest": true, "text-moderation-latest": true,
+	"babbage-002": true, "davinci-002": true,
+}
+
+// foreignModelPrefixes 其他厂商模型的名称前缀，出现在模型列表中说明是聚合多家模型的中转
+var foreignModelPrefixes = []string{
+	"claude", "gemini", "gemma", "deepseek", "qwen", "glm-", "chatglm", "moonshot", "kimi",
+	"llama", "mistral", "mixtral", "grok", "yi-", "ernie", "doubao", "abab", "minimax",
+	"hunyuan", "spark", "baichuan", "command-r", "sonar",
+}
+
+// unknownModelsShown 证据中最多列出的目录外模型数量
+const unknownModelsShown = 10
+
+// officialOwners 官方模型列表中owned_by的取值；微调模型的所有者为组织或用户ID
+var officialOwners = map[string]bool{
+	"system": true, "openai": true, "openai-internal": true, "openai-dev": true,
+}
+
+func (modelsCheck) Run(ctx context.Context, d *Detector) Outcome {
+	resp, err := d.fetchModels(ctx)
+	if err != nil {
+		return errorOutcome(err)
+	}
+
+	var out Outcome
+	out.set("url", ModelsURL(d.config.Endpoint))
+	if !out.expect(resp.StatusCode == 200, "模型列表端点返回状态码%d", resp.StatusCode) {
+		return out.conclude("", "未实现/v1/models端点")
+	}
+
+	var list map[string]interface{}
+	if !out.expect(json.Unmarshal(resp.Body, &list) == nil, "响应体为JSON对象") {
+		return out.conclude("", "模型列表不是JSON对象")
+	}
+	object := stringField(list, "object")
+	out.expect(object == "list", "object为list（实际为%q）", object)
+	data, ok := list["data"].([]interface{})
+	if !out.expect(ok && len(data) > 0, "data为非空数组（共%d个模型）", len(data)) {
+		return out.conclude("", "模型列表为空或格式错误")
+	}
+
+	devs := newDeviations(&out, modelsEvidenceCap)
+	latest := time.Now().Add(maxClockSkew).Unix()
+	var ids, chatModels, unknown, foreign []string
+	for i, item := range data {
+		model, ok := item.(map[string]interface{})
+		if !ok {
+			devs.add("shape", "第%d项不是对象", i+1)
+			continue
+		}
+		id := stringField(model, "id")
+		if id == "" {
+			devs.add("shape", "第%d项缺少id", i+1)
+			continue
+		}
+		ids = append(ids, id)
+		if IsChatModel(id) {
+			chatModels = append(chatModels, id)
+		}
+
+		if o := stringField(model, "object"); o != "model" {
+			devs.add("shape", "%s的object为%q，应为model", id, o)
+		}
+		if created, ok := model["created"].(float64); !ok || created <= 0 || int64(created) > latest {
+			devs.add("shape", "%s的created无效: %v", id, model["created"])
+		}
+
+		owner := stringField(model, "owned_by")
+		fineTuned := strings.HasPrefix(id, "ft:")
+		if owner == "" {
+			devs.add("shape", "%s缺少owned_by", id)
+		} else if !fineTuned && !officialOwners[owner] {
+			devs.add("owned_by", "%s的owned_by为%q，不是官方取值", id, owner)
+		}
+
+		// 微调模型由用户创建，不在官方目录中
+		switch {
+		case fineTuned || isOfficialModel(id):
+		case isForeignModel(id):
+			foreign = append(foreign, id)
+			devs.add("foreign", "列表包含其他厂商的模型%q", id)
+		default:
+			unknown = append(unknown, id)
+		}
+	}
+	devs.passIfNone("shape", "每个模型都包含id、object、created和owned_by字段")
+	devs.passIfNone("owned_by", "owned_by均为官方取值")
+	devs.passIfNone("foreign", "列表中没有其他厂商的模型")
+	devs.summarize("shape", "owned_by", "foreign")
+	if len(unknown) > 0 {
+		shown := unknown
+		if len(shown) > unknownModelsShown {
+			shown = shown[:unknownModelsShown]
+		}
+		out.note("%d个模型不在内置的官方模型目录中（可能是新发布的模型）: %s", len(unknown), strings.Join(shown, ", "))
+	}
+
+	// 所检测的模型应出现在列表中
+	listed := false
+	for _, id := range ids {
+		if id == d.config.Model {
+			listed = true
+			break
+		}
+	}
+	out.expect(listed, "列表包含所检测的模型%q", d.config.Model)
+
+	sort.Strings(chatModels)
+	out.set("model_count", len(ids))
+	out.set("chat_models", chatModels)
+	out.set("unknown_models", unknown)
+	out.set("foreign_models", foreign)
+
+	log.Printf("Models检测: 共%d个模型, 聊天模型%d个, 目录外模型%d个, 其他厂商模型%d个", len(ids), len(chatModels), len(unknown), len(foreign))
+
+	if len(foreign) > 0 {
+		return out.conclude("", "模型列表包含其他厂商的模型")
+	}
+	return out.conclude("模型列表与官方API一致", "模型列表与官方API不一致")
+}
+
+// isOfficialModel 判断模型是否为官方目录中的模型或其快照版本
+func isOfficialModel(id string) bool {
+	if officialModels[id] {
+		return true
+	}
+	// 去掉-YYYY-MM-DD或-MMDD后缀后查找基础模型
+	for _, n := range []int{len("-2024-07-18"), len("-0613")} {
+		if len(id) > n {
+			base := id[:len(id)-n]
+			if officialModels[base] && isModelSnapshot(base, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isForeignModel 判断模型是否明显来自其他厂商
+func isForeignModel(id string) bool {
+	name := normalizeModelName(id)
+	for _, prefix := range foreignModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package detector
+
+import "testing"
+
+func TestIsOfficialModel(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"gpt-4o-mini", true},
+		{"gpt-4o-mini-2024-07-18", true},
+		{"gpt-4-0613", true},
+		{"gpt-3.5-turbo-0125", true},
+		{"o3-mini-2025-01-31", true},
+		{"gpt-4o-mini-search-preview-2025-03-11", true},
+		{"gpt-5-ultra", false},
+		{"gpt-4o-2024-13", false},
+		{"gpt-4o-mini-2024-07-18-custom", false},
+		{"claude-3-opus", false},
+	}
+
+	for _, tt := range tests {
+		if got := isOfficialModel(tt.id); got != tt.want {
+			t.Errorf("isOfficialModel(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestIsForeignModel(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"claude-3-5-sonnet-20241022", true},
+		{"gemini-1.5-pro", true},
+		{"deepseek-chat", true},
+		{"Qwen2.5-72B-Instruct", true},
+		{"meta-llama/llama-3-70b", true},
+		{"glm-4", true},
+		{"gpt-4o", false},
+		{"o1-mini", false},
+		{"my-custom-model", false},
+	}
+
+	for _, tt := range tests {
+		if got := isForeignModel(tt.id); got != tt.want {
+			t.Errorf("isForeignModel(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestModelsURL(t *testing.T) {
+	tests := []struct {
+		endpoint, want string
+	}{
+		{"https://api.openai.com/v1/chat/completions", "https://api.openai.com/v1/models"},
+		{"https://relay.example.com/v1/chat/completions/", "https://relay.example.com/v1/models"},
+		{"https://relay.example.com/openai/v1/chat/completions", "https://relay.example.com/openai/v1/models"},
+		{"https://relay.example.com/api/chat?key=x", "https://relay.example.com/v1/models"},
+		{"http://127.0.0.1:8080", "http://127.0.0.1:8080/v1/models"},
+	}
+
+	for _, tt := range tests {
+		if got := ModelsURL(tt.endpoint); got != tt.want {
+			t.Errorf("ModelsURL(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
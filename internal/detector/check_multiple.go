@@ -0,0 +1,131 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+func init() {
+	Register(multipleCheck{})
+}
+
+// multipleCheck 检查n参数是否生效
+//
+// 只看choices的数量不够：中转可以并发或串行发起n次请求再拼接结果。
+// 官方API的n个choice来自同一次调用，index为0..n-1，completion_tokens为
+// 各choice之和而prompt_tokens只计一次，耗时也与单次调用相当。
+type multipleCheck struct{}
+
+func (multipleCheck) Name() string { return "multiple" }
+func (multipleCheck) Description() string {
+	return "检测n参数返回的多个结果是否来自同一次调用（数量、index、用量和耗时）"
+}
+func (multipleCheck) Weight() int { return 2 }
+
+// Timeout n=1和n>1的请求依次发送
+func (multipleCheck) Timeout(base time.Duration) time.Duration { return 2 * base }
+
+const (
+	// requestedChoices 请求的choice数量
+	requestedChoices = 3
+	// maxLatencyRatio n个choice与单个choice的耗时比上限，串行拼接的耗时约为n倍
+	maxLatencyRatio = 2.0
+	// latencySlack 耗时差值低于该值时视为网络抖动，不按比例判断
+	latencySlack = time.Second
+)
+
+func (multipleCheck) Run(ctx context.Context, d *Detector) Outcome {
+	messages := []map[string]string{{"role": "user", "content": "Tell me a short joke"}}
+
+	// 先发送n=1的请求作为基准，再发送n=3的请求
+	baseline, baseElapsed, err := requestChoices(ctx, d, messages, 1)
+	if err != nil {
+		return errorOutcome(err)
+	}
+	response, elapsed, err := requestChoices(ctx, d, messages, requestedChoices)
+	if err != nil {
+		return errorOutcome(err)
+	}
+	if _, ok := response["choices"].([]interface{}); !ok {
+		return errorOutcome(fmt.Errorf("无法解析响应格式"))
+	}
+	choices := choicesOf(response)
+
+	// 简洁的日志输出
+	log.Printf("Multiple检测: 请求n=%d, 实际返回n=%d", requestedChoices, len(choices))
+
+	var out Outcome
+	out.set("choices", len(choices))
+	out.expect(len(choices) == requestedChoices, "请求n=%d，实际返回%d个choice", requestedChoices, len(choices))
+
+	// index应恰好为0..n-1，各出现一次
+	seen := make(map[int]bool)
+	validIndex := true
+	for _, choice := range choices {
+		idx, ok := choice["index"].(float64)
+		if !ok || idx < 0 || int(idx) >= len(choices) || seen[int(idx)] {
+			validIndex = false
+			break
+		}
+		seen[int(idx)] = true
+	}
+	out.expect(validIndex, "choice的index恰好为0..%d且不重复", len(choices)-1)
+
+	// completion_tokens应为各choice的token数之和
+	localSum := 0
+	distinct := make(map[string]bool)
+	for _, choice := range choices {
+		content, _ := messageContent(choice)
+		n, err := d.countTokens(content)
+		if err != nil {
+			return errorOutcome(err)
+		}
+		localSum += n
+		distinct[content] = true
+	}
+	completion := usageInt(response, "completion_tokens") - reasoningTokens(response)
+	out.expect(completion == localSum, "completion_tokens(%d)等于各choice的token数之和(%d)", completion, localSum)
+
+	// prompt_tokens只计一次，应与n=1时相同
+	prompt := usageInt(response, "prompt_tokens")
+	basePrompt := usageInt(baseline, "prompt_tokens")
+	out.expect(prompt == basePrompt, "prompt_tokens(%d)与n=1时(%d)相同，只计费一次", prompt, basePrompt)
+	if expected, err := d.countChatTokens(messages); err == nil && prompt != expected {
+		out.note("本地计算的prompt_tokens为%d", expected)
+	}
+
+	// 耗时应与单次调用相当
+	ratio := elapsed.Seconds() / baseElapsed.Seconds()
+	out.set("latency_ms", elapsed.Milliseconds())
+	out.set("baseline_latency_ms", baseElapsed.Milliseconds())
+	out.expect(ratio <= maxLatencyRatio || elapsed-baseElapsed < latencySlack, "n=%d耗时%dms，为n=1时(%dms)的%.1f倍", requestedChoices, elapsed.Milliseconds(), baseElapsed.Milliseconds(), ratio)
+
+	// 多样性仅作参考，温度为1时也可能出现相同的回答
+	out.set("distinct_choices", len(distinct))
+	out.note("%d个choice中有%d个不同的回答", len(choices), len(distinct))
+
+	return out.conclude("n个choice来自同一次调用", "n参数未生效或结果由多次调用拼接")
+}
+
+// requestChoices 发送请求n个回答的请求，返回响应和耗时
+func requestChoices(ctx context.Context, d *Detector, messages []map[string]string, n int) (map[string]interface{}, time.Duration, error) {
+	req := map[string]interface{}{
+		"model":       d.config.Model,
+		"messages":    messages,
+		"n":           n,
+		"temperature": 1.0, // 高温度增加多样性
+		"max_tokens":  60,
+	}
+
+	resp, err := d.sendRequest(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	var response map[string]interface{}
+	if err := resp.decode(&response); err != nil {
+		return nil, 0, err
+	}
+	return response, resp.Elapsed, nil
+}
@@ -0,0 +1,83 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+)
+
+func init() {
+	Register(promptTokensCheck{})
+}
+
+// promptTokensCheck 检查usage.prompt_tokens是否与本地计算的输入token数一致
+//
+// 中转注入隐藏的系统提示词或虚报输入token时，prompt_tokens会明显偏大。
+type promptTokensCheck struct{}
+
+func (promptTokensCheck) Name() string { return "prompt_tokens" }
+func (promptTokensCheck) Description() string {
+	return "检测输入token计费是否与本地计算一致，发现隐藏系统提示词或虚报输入token"
+}
+func (promptTokensCheck) Weight() int { return 3 }
+
+// promptTokenTolerance 允许的输入token偏差，超过即视为多计费
+const promptTokenTolerance = 2
+
+// hiddenPromptThreshold 输入token多出该数量时，判断为注入了隐藏的系统提示词
+const hiddenPromptThreshold = 20
+
+func (promptTokensCheck) Run(ctx context.Context, d *Detector) Outcome {
+	messages := []map[string]string{
+		{"role": "system", "content": "You are a concise assistant. Reply with a single word."},
+		{"role": "user", "content": "What color is the sky on a clear day?"},
+	}
+	req := map[string]interface{}{
+		"model":      d.config.Model,
+		"messages":   messages,
+		"max_tokens": 5,
+	}
+
+	expected, err := d.countChatTokens(messages)
+	if err != nil {
+		return errorOutcome(err)
+	}
+
+	var response map[string]interface{}
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+
+	actual := usageInt(response, "prompt_tokens")
+	if actual < 0 {
+		return errorOutcome(fmt.Errorf("响应中缺少usage.prompt_tokens"))
+	}
+
+	delta := actual - expected
+	overcharge := float64(delta) / float64(expected) * 100
+	overcharge = math.Round(overcharge*10) / 10
+
+	var out Outcome
+	out.set("expected_prompt_tokens", expected)
+	out.set("api_prompt_tokens", actual)
+	out.set("delta_tokens", delta)
+	out.set("overcharge_percent", overcharge)
+
+	log.Printf("PromptTokens检测: 本地计算=%d, API返回=%d, 差值=%d (%.1f%%)", expected, actual, delta, overcharge)
+
+	out.expect(delta <= promptTokenTolerance && delta >= -promptTokenTolerance,
+		"prompt_tokens=%d，本地计算%d，相差%+d个token（允许±%d）", actual, expected, delta, promptTokenTolerance)
+	if delta > promptTokenTolerance {
+		out.note("预计多计费%.1f%%", overcharge)
+	}
+
+	switch {
+	case delta > hiddenPromptThreshold:
+		out.Message = fmt.Sprintf("输入多出%d个token（多计费%.1f%%），疑似注入了隐藏的系统提示词", delta, overcharge)
+	case delta > promptTokenTolerance:
+		out.Message = fmt.Sprintf("输入token虚报%d个（多计费%.1f%%）", delta, overcharge)
+	}
+
+	return out.conclude("输入token计费与本地计算一致", "输入token计费与本地计算不一致")
+}
@@ -0,0 +1,123 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+func init() {
+	Register(seedCheck{})
+}
+
+// seedCheck 检查seed参数下输出的确定性和system_fingerprint字段
+//
+// 官方API的每个响应都带有system_fingerprint，标识生成该响应的后端配置，
+// 短时间内使用相同seed的两次请求应得到相同的指纹。官方文档只承诺"尽力"
+// 确定，因此输出和logprobs的差异仅作为参考，不计入判定。
+type seedCheck struct{}
+
+func (seedCheck) Name() string { return "seed" }
+func (seedCheck) Description() string {
+	return "使用固定seed发送两次相同请求，检测system_fingerprint是否存在且一致，并比较输出和logprobs"
+}
+func (seedCheck) Weight() int { return 2 }
+
+// Timeout 两次相同的请求依次发送
+func (seedCheck) Timeout(base time.Duration) time.Duration { return 2 * base }
+
+// seedValue 检测使用的固定seed
+const seedValue = 42
+
+func (seedCheck) Run(ctx context.Context, d *Detector) Outcome {
+	if out, skip := skipForReasoning(d, "temperature=0和logprobs"); skip {
+		return out
+	}
+
+	req := map[string]interface{}{
+		"model":       d.config.Model,
+		"messages":    []map[string]string{{"role": "user", "content": "Write a short sentence about the ocean."}},
+		"seed":        seedValue,
+		"temperature": 0,
+		"max_tokens":  40,
+		"logprobs":    true,
+	}
+
+	// 连续发送两次相同的请求
+	var responses [2]map[string]interface{}
+	for i := range responses {
+		if err := d.makeRequest(ctx, req, &responses[i]); err != nil {
+			return errorOutcome(err)
+		}
+	}
+
+	var out Outcome
+
+	// system_fingerprint应存在且两次一致
+	fingerprints := make([]string, len(responses))
+	for i, response := range responses {
+		fingerprints[i] = stringField(response, "system_fingerprint")
+		out.expect(fingerprints[i] != "", "第%d次响应包含system_fingerprint: %q", i+1, fingerprints[i])
+	}
+	out.set("system_fingerprints", fingerprints)
+	if fingerprints[0] != "" && fingerprints[1] != "" {
+		out.expect(fingerprints[0] == fingerprints[1], "两次响应的system_fingerprint一致")
+	}
+
+	// 输出内容是否一致
+	var contents [2]string
+	var logprobs [2][]interface{}
+	for i, response := range responses {
+		choice, ok := firstChoice(response)
+		if !ok {
+			return errorOutcome(fmt.Errorf("无法解析响应格式"))
+		}
+		contents[i], _ = messageContent(choice)
+		if lp, ok := choice["logprobs"].(map[string]interface{}); ok {
+			logprobs[i], _ = lp["content"].([]interface{})
+		}
+	}
+	identical := contents[0] == contents[1]
+	out.set("outputs_identical", identical)
+	if identical {
+		out.note("两次输出完全一致")
+	} else {
+		out.note("两次输出不一致: %q / %q", previewString(contents[0], 40), previewString(contents[1], 40))
+	}
+
+	// 比较两次输出中相同位置token的logprob
+	if len(logprobs[0]) > 0 && len(logprobs[1]) > 0 {
+		delta, compared := logprobsDelta(logprobs[0], logprobs[1])
+		out.set("max_logprob_delta", delta)
+		out.note("比较了%d个相同位置的token，logprob最大差值为%.6f", compared, delta)
+	} else {
+		out.note("响应未包含logprobs，跳过概率比较")
+	}
+
+	log.Printf("Seed检测: system_fingerprint=%v, 输出一致=%v", fingerprints, identical)
+
+	return out.conclude("system_fingerprint存在且稳定", "system_fingerprint缺失或不稳定")
+}
+
+// logprobsDelta 比较两组logprobs中token相同的前缀部分，返回logprob的最大差值和比较的token数
+func logprobsDelta(a, b []interface{}) (float64, int) {
+	maxDelta := 0.0
+	compared := 0
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ea, _ := a[i].(map[string]interface{})
+		eb, _ := b[i].(map[string]interface{})
+		if ea["token"] != eb["token"] {
+			break
+		}
+		la, okA := ea["logprob"].(float64)
+		lb, okB := eb["logprob"].(float64)
+		if !okA || !okB {
+			continue
+		}
+		maxDelta = math.Max(maxDelta, math.Abs(la-lb))
+		compared++
+	}
+	return maxDelta, compared
+}
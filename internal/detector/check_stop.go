@@ -0,0 +1,118 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(stopCheck{})
+}
+
+// stopCheck 检查stop参数是否生效
+//
+// 让模型从1数到20，这是几乎必然会生成的已知序列，在其中选取停止序列：
+// 官方API应在停止序列之前截断输出，finish_reason为stop，且completion_tokens
+// 与截断后的文本一致（停止序列所在的token也会计费，因此允许多1个）。
+type stopCheck struct{}
+
+func (stopCheck) Name() string { return "stop" }
+func (stopCheck) Description() string {
+	return "让模型从1数到20，检测API是否在指定的停止序列处截断输出并正确计费"
+}
+func (stopCheck) Weight() int { return 3 }
+
+// Timeout 每组停止序列依次发送，各计一份超时时间
+func (stopCheck) Timeout(base time.Duration) time.Duration {
+	return base * time.Duration(len(stopCases))
+}
+
+// stopCase 一组停止序列及截断后应出现的最后一个数字
+type stopCase struct {
+	stop []string
+	last int
+}
+
+var stopCases = []stopCase{
+	{stop: []string{"7"}, last: 6},
+	{stop: []string{"xyz", "5"}, last: 4}, // 多个停止序列时，任何一个出现都应停止
+}
+
+var numberPattern = regexp.MustCompile(`\d+`)
+
+func (stopCheck) Run(ctx context.Context, d *Detector) Outcome {
+	if out, skip := skipForReasoning(d, "stop和temperature=0"); skip {
+		return out
+	}
+
+	var out Outcome
+	outputs := make(map[string]string, len(stopCases))
+
+	for _, tc := range stopCases {
+		req := map[string]interface{}{
+			"model":       d.config.Model,
+			"messages":    []map[string]string{{"role": "user", "content": "Count from 1 to 20, separated by commas. Output only the numbers."}},
+			"stop":        tc.stop,
+			"temperature": 0,
+			"max_tokens":  100,
+		}
+
+		var response map[string]interface{}
+		if err := d.makeRequest(ctx, req, &response); err != nil {
+			return errorOutcome(err)
+		}
+
+		choice, ok := firstChoice(response)
+		if !ok {
+			return errorOutcome(fmt.Errorf("无法解析响应格式"))
+		}
+		content, ok := messageContent(choice)
+		if !ok {
+			return errorOutcome(fmt.Errorf("无法解析响应格式"))
+		}
+
+		label := fmt.Sprintf("stop=%q", tc.stop)
+		outputs[strings.Join(tc.stop, ",")] = content
+		log.Printf("Stop检测: 设置%s, 内容片段: %s", label, previewString(content, 40))
+
+		// 输出应是1到last的连续数字，且不包含任何停止序列
+		for _, s := range tc.stop {
+			out.expect(!strings.Contains(content, s), "%s: 输出不包含停止序列%q", label, s)
+		}
+		out.expect(isCountUpTo(content, tc.last), "%s: 输出在%d之后截断: %q", label, tc.last, previewString(content, 60))
+
+		finishReason := stringField(choice, "finish_reason")
+		out.expect(finishReason == "stop", "%s: finish_reason为stop（实际为%q）", label, finishReason)
+
+		// 停止序列所在的token也会计入completion_tokens
+		local, err := d.countTokens(content)
+		if err != nil {
+			return errorOutcome(err)
+		}
+		completion := usageInt(response, "completion_tokens")
+		out.expect(completion == local || completion == local+1,
+			"%s: completion_tokens(%d)与截断后文本的token数(%d)一致", label, completion, local)
+	}
+
+	out.set("outputs", outputs)
+	return out.conclude("stop参数生效，输出在停止序列处截断", "stop参数未生效或截断不正确")
+}
+
+// isCountUpTo 判断文本中的数字是否恰好为1到last的连续序列
+func isCountUpTo(content string, last int) bool {
+	numbers := numberPattern.FindAllString(content, -1)
+	if len(numbers) != last {
+		return false
+	}
+	for i, s := range numbers {
+		if n, err := strconv.Atoi(s); err != nil || n != i+1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package detector
+
+import "testing"
+
+func TestIsCountUpTo(t *testing.T) {
+	tests := []struct {
+		content string
+		last    int
+		want    bool
+	}{
+		{"1, 2, 3, 4, 5, 6, ", 6, true},
+		{"1,2,3,4", 4, true},
+		{"1\n2\n3\n4", 4, true},
+		{"Sure! 1, 2, 3, 4", 4, true},
+		{"", 0, true},
+		{"1, 2, 3, 4, 5, 6, 7", 6, false},
+		{"1, 2, 3, 4, 5", 6, false},
+		{"1, 2, 4, 5, 6, 7", 6, false},
+		{"2, 3, 4, 5, 6, 7", 6, false},
+		{"1, 2, 3, 4, 5, 66", 6, false},
+	}
+
+	for _, tt := range tests {
+		if got := isCountUpTo(tt.content, tt.last); got != tt.want {
+			t.Errorf("isCountUpTo(%q, %d) = %v, want %v", tt.content, tt.last, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package detector
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+func init() {
+	Register(streamCheck{})
+}
+
+// streamCheck 检查流式响应(SSE)是否符合官方API的格式
+//
+// 逆向网页版的中转API最容易在流式输出上露出破绽，例如缺少[DONE]、
+// 每个数据块的id不一致或role出现在多个数据块中。
+type streamCheck struct{}
+
+func (streamCheck) Name() string { return "stream" }
+func (streamCheck) Description() string {
+	return "检测流式响应(SSE)的帧格式和数据块字段是否与官方API一致"
+}
+func (streamCheck) Weight() int { return 3 }
+
+// streamDeviationLimit 限制同类偏差记录的条数
+const streamDeviationLimit = 3
+
+func (streamCheck) Run(ctx context.Context, d *Detector) Outcome {
+	req := map[string]interface{}{
+		"model":      d.config.Model,
+		"messages":   []map[string]string{{"role": "user", "content": "Write a haiku about the sea."}},
+		"max_tokens": 100,
+	}
+
+	stream, err := d.streamRequest(ctx, req)
+	if err != nil {
+		return errorOutcome(err)
+	}
+
+	var out Outcome
+	out.set("chunks", len(stream.Chunks))
+	out.set("done", stream.Done)
+
+	log.Printf("Stream检测: 数据块=%d, [DONE]=%v, 格式问题=%d", len(stream.Chunks), stream.Done, len(stream.Problems))
+
+	// SSE帧格式
+	out.expect(strings.HasPrefix(stream.ContentType, "text/event-stream"), "Content-Type为text/event-stream（实际为%q）", stream.ContentType)
+	if len(stream.Problems) == 0 {
+		out.expect(true, "所有事件均使用标准的data:帧格式")
+	}
+	for _, p := range stream.Problems {
+		out.expect(false, "帧格式问题: %s", p)
+	}
+	out.expect(stream.Done, "流以data: [DONE]结束")
+
+	if !out.expect(len(stream.Chunks) > 0, "收到%d个数据块", len(stream.Chunks)) {
+		return out.conclude("", "未收到任何数据块")
+	}
+
+	// Azure OpenAI会先发送只包含prompt_filter_results的数据块（choices和id均为空），
+	// 这些前导数据块不参与字段一致性比较
+	start := 0
+	for start < len(stream.Chunks) && isPromptFilterChunk(stream.Chunks[start].Data) {
+		start++
+	}
+	if start > 0 {
+		out.note("跳过%d个前导的内容过滤数据块（Azure OpenAI）", start)
+	}
+	if !out.expect(start < len(stream.Chunks), "收到内容数据块") {
+		return out.conclude("", "未收到任何内容数据块")
+	}
+
+	// 数据块字段一致性
+	first := stream.Chunks[start].Data
+	id, model, created := stringField(first, "id"), stringField(first, "model"), first["created"]
+	out.expect(id != "", "数据块包含id字段")
+	out.set("id", id)
+	out.set("model", model)
+
+	devs := newDeviations(&out, streamDeviationLimit)
+
+	lastWithChoices := -1
+	seenChoice := false
+	for i := start; i < len(stream.Chunks); i++ {
+		data := stream.Chunks[i].Data
+		if obj := stringField(data, "object"); obj != "chat.completion.chunk" {
+			devs.add("object", "第%d个数据块的object为%q，应为chat.completion.chunk", i+1, obj)
+		}
+		if v := stringField(data, "id"); v != id {
+			devs.add("id", "第%d个数据块的id(%q)与首个数据块(%q)不一致", i+1, v, id)
+		}
+		if v := stringField(data, "model"); v != model {
+			devs.add("model", "第%d个数据块的model(%q)与首个数据块(%q)不一致", i+1, v, model)
+		}
+		if v := data["created"]; v != created {
+			devs.add("created", "第%d个数据块的created(%v)与首个数据块(%v)不一致", i+1, v, created)
+		}
+
+		choice, ok := firstChoice(data)
+		if !ok {
+			continue
+		}
+		lastWithChoices = i
+
+		delta, ok := choice["delta"].(map[string]interface{})
+		if !ok {
+			devs.add("delta", "第%d个数据块缺少delta对象", i+1)
+			continue
+		}
+		// role只应出现在第一个带choices的数据块中
+		role, hasRole := delta["role"]
+		if !seenChoice {
+			seenChoice = true
+			out.expect(role == "assistant", "首个数据块的delta.role为assistant（实际为%v）", role)
+		} else if hasRole {
+			devs.add("role", "第%d个数据块的delta中重复出现role(%v)", i+1, role)
+		}
+	}
+
+	for _, kind := range []string{"object", "id", "model", "created", "role"} {
+		devs.passIfNone(kind, "所有数据块的%s字段符合要求", kind)
+	}
+
+	// finish_reason只应出现在最后一个数据块中
+	if !out.expect(lastWithChoices >= 0, "数据块包含choices") {
+		return out.conclude("", "流式响应不符合官方格式")
+	}
+	for i := start; i < lastWithChoices; i++ {
+		if choice, ok := firstChoice(stream.Chunks[i].Data); ok && choice["finish_reason"] != nil {
+			devs.add("finish_reason", "第%d个数据块提前出现finish_reason(%v)", i+1, choice["finish_reason"])
+		}
+	}
+	finalChoice, _ := firstChoice(stream.Chunks[lastWithChoices].Data)
+	finishReason := stringField(finalChoice, "finish_reason")
+	out.set("finish_reason", finishReason)
+	out.expect(finishReason == "stop" || finishReason == "length", "最后一个数据块的finish_reason为%q", finishReason)
+
+	devs.summarize("object", "id", "model", "created", "delta", "role", "finish_reason")
+
+	log.Printf("Stream检测: 内容片段: %s", previewString(stream.content(), 40))
+
+	return out.conclude("流式响应符合官方格式", "流式响应不符合官方格式")
+}
+
+// isPromptFilterChunk 判断数据块是否为Azure OpenAI在流开头发送的内容过滤结果
+func isPromptFilterChunk(data map[string]interface{}) bool {
+	return len(choicesOf(data)) == 0 && stringField(data, "id") == ""
+}
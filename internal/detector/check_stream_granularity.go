@@ -0,0 +1,176 @@
+package detector
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+)
+
+func init() {
+	Register(streamGranularityCheck{})
+}
+
+// streamGranularityCheck 检查流式输出的数据块粒度和时间分布
+//
+// 官方API大致每个数据块输出一个token且间隔均匀；代理网页版会话的中转
+// 往往一次输出一大段文字，或先缓存再集中突发推送。
+//
+// 到达时间在解析每个SSE帧时记录，同一次网络读取中的多个帧间隔接近0，
+// 官方API经过代理或网络抖动时也会如此，因此突发占比只作为参考，只按token粒度判定。
+type streamGranularityCheck struct{}
+
+func (streamGranularityCheck) Name() string { return "stream_granularity" }
+func (streamGranularityCheck) Description() string {
+	return "检测流式数据块的token粒度和到达时间分布是否与官方API一致"
+}
+func (streamGranularityCheck) Weight() int { return 2 }
+
+// 粒度和时间分布的判定阈值
+const (
+	maxMeanTokensPerChunk = 2.0 // 每个数据块的平均token数上限
+	maxLargeChunkRatio    = 0.2 // 超过3个token的数据块占比上限
+	maxBurstRatio         = 0.5 // 与上一数据块几乎同时到达的数据块占比超过该值时提示疑似集中推送
+	burstGap              = time.Millisecond
+	minGranularityTokens  = 20 // 输出少于该token数时无法判断
+)
+
+// HistogramBucket 表示直方图中的一个区间
+type HistogramBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// histogram 按给定的区间上界统计数值分布，最后一个区间不设上界
+func histogram(values []float64, bounds []float64, labels []string) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(labels))
+	for i, label := range labels {
+		buckets[i].Label = label
+	}
+	for _, v := range values {
+		i := 0
+		for i < len(bounds) && v > bounds[i] {
+			i++
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+func (streamGranularityCheck) Run(ctx context.Context, d *Detector) Outcome {
+	req := map[string]interface{}{
+		"model":      d.config.Model,
+		"messages":   []map[string]string{{"role": "user", "content": "Write a paragraph of about 120 words about the history of the printing press."}},
+		"max_tokens": 200,
+	}
+
+	stream, err := d.streamRequest(ctx, req)
+	if err != nil {
+		return errorOutcome(err)
+	}
+
+	// 统计每个含内容的数据块的token数和到达间隔
+	var tokenCounts, gapsMs []float64
+	var last time.Duration
+	totalTokens, largeChunks, bursts := 0, 0, 0
+	firstToken := time.Duration(-1)
+	for _, chunk := range stream.Chunks {
+		delta, ok := chunkDelta(chunk.Data)
+		if !ok {
+			continue
+		}
+		content, _ := delta["content"].(string)
+		if content == "" {
+			continue
+		}
+
+		n, err := d.countTokens(content)
+		if err != nil {
+			return errorOutcome(err)
+		}
+		tokenCounts = append(tokenCounts, float64(n))
+		totalTokens += n
+		if n > 3 {
+			largeChunks++
+		}
+
+		if firstToken < 0 {
+			firstToken = chunk.Elapsed
+		} else {
+			gap := chunk.Elapsed - last
+			gapsMs = append(gapsMs, float64(gap.Microseconds())/1000)
+			if gap < burstGap {
+				bursts++
+			}
+		}
+		last = chunk.Elapsed
+	}
+
+	if totalTokens < minGranularityTokens {
+		return skipOutcome("流式输出仅%d个token，不足以判断粒度", totalTokens)
+	}
+
+	var out Outcome
+	chunks := len(tokenCounts)
+	meanTokens := float64(totalTokens) / float64(chunks)
+	largeRatio := float64(largeChunks) / float64(chunks)
+	burstRatio := 0.0
+	if len(gapsMs) > 0 {
+		burstRatio = float64(bursts) / float64(len(gapsMs))
+	}
+
+	out.set("content_chunks", chunks)
+	out.set("total_tokens", totalTokens)
+	out.set("mean_tokens_per_chunk", math.Round(meanTokens*100)/100)
+	out.set("ttft_ms", firstToken.Milliseconds())
+	out.set("burst_ratio", math.Round(burstRatio*100)/100)
+	out.set("token_histogram", histogram(tokenCounts,
+		[]float64{1, 2, 3, 5, 10},
+		[]string{"1", "2", "3", "4-5", "6-10", ">10"}))
+	out.set("gap_histogram", histogram(gapsMs,
+		[]float64{1, 10, 50, 100, 250, 500},
+		[]string{"<1ms", "1-10ms", "10-50ms", "50-100ms", "100-250ms", "250-500ms", ">500ms"}))
+
+	log.Printf("StreamGranularity检测: 数据块=%d, 总tokens=%d, 平均每块=%.2f, 突发占比=%.2f",
+		chunks, totalTokens, meanTokens, burstRatio)
+
+	out.expect(meanTokens <= maxMeanTokensPerChunk, "平均每个数据块%.2f个token（官方API约为1，上限%.1f）", meanTokens, maxMeanTokensPerChunk)
+	out.expect(largeRatio <= maxLargeChunkRatio, "超过3个token的数据块占%.0f%%（上限%.0f%%）", largeRatio*100, maxLargeChunkRatio*100)
+	if len(gapsMs) > 0 {
+		out.note("%.0f%%的数据块与上一块间隔不足%s，%s", burstRatio*100, burstGap, burstDescription(burstRatio))
+		mean, cv := meanAndCV(gapsMs)
+		out.note("数据块平均间隔%.1fms，变异系数%.2f，首个token耗时%dms", mean, cv, firstToken.Milliseconds())
+	}
+
+	return out.conclude("流式粒度与官方API一致", "流式粒度与官方API不一致，疑似代理网页版会话")
+}
+
+// burstDescription 描述突发推送的程度
+func burstDescription(ratio float64) string {
+	if ratio > maxBurstRatio {
+		return "数据块疑似被缓存后集中推送"
+	}
+	return "数据块逐个到达"
+}
+
+// meanAndCV 返回数值的平均值和变异系数
+func meanAndCV(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if mean == 0 {
+		return 0, 0
+	}
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(values)))
+	return mean, stddev / mean
+}
@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"context"
+	"log"
+)
+
+func init() {
+	Register(streamUsageCheck{})
+}
+
+// streamUsageCheck 检查stream_options.include_usage返回的用量统计
+//
+// 官方API会在[DONE]之前额外发送一个choices为空、带有usage的数据块；
+// 很多中转会省略该数据块或伪造用量，而计费正是基于这里的数字。
+type streamUsageCheck struct{}
+
+func (streamUsageCheck) Name() string { return "stream_usage" }
+func (streamUsageCheck) Description() string {
+	return "检测流式请求中include_usage返回的用量统计是否存在且与实际输出一致"
+}
+func (streamUsageCheck) Weight() int { return 3 }
+
+func (streamUsageCheck) Run(ctx context.Context, d *Detector) Outcome {
+	req := map[string]interface{}{
+		"model":          d.config.Model,
+		"messages":       []map[string]string{{"role": "user", "content": "Name the three primary colors of light, one per line."}},
+		"max_tokens":     50,
+		"stream_options": map[string]interface{}{"include_usage": true},
+	}
+
+	stream, err := d.streamRequest(ctx, req)
+	if err != nil {
+		return errorOutcome(err)
+	}
+
+	var out Outcome
+	if !out.expect(len(stream.Chunks) > 0, "收到%d个数据块", len(stream.Chunks)) {
+		return out.conclude("", "未收到任何数据块")
+	}
+
+	// 用量数据块应是[DONE]之前的最后一个数据块
+	final := stream.Chunks[len(stream.Chunks)-1].Data
+	choices, hasChoices := final["choices"].([]interface{})
+	_, hasUsage := final["usage"].(map[string]interface{})
+	out.expect(hasChoices && len(choices) == 0, "最后一个数据块的choices为空数组")
+	if !out.expect(hasUsage, "最后一个数据块包含usage对象") {
+		return out.conclude("", "未返回流式用量统计")
+	}
+
+	// 其余数据块不应携带用量
+	early := 0
+	for _, chunk := range stream.Chunks[:len(stream.Chunks)-1] {
+		if u, ok := chunk.Data["usage"].(map[string]interface{}); ok && len(u) > 0 {
+			early++
+		}
+	}
+	out.expect(early == 0, "只有最后一个数据块携带usage（其余%d个数据块携带了usage）", early)
+
+	prompt := usageInt(final, "prompt_tokens")
+	completion := usageInt(final, "completion_tokens")
+	total := usageInt(final, "total_tokens")
+	out.set("prompt_tokens", prompt)
+	out.set("completion_tokens", completion)
+	out.set("total_tokens", total)
+
+	out.expect(prompt > 0 && completion >= 0 && total >= 0, "usage包含prompt_tokens、completion_tokens和total_tokens")
+	out.expect(total == prompt+completion, "total_tokens(%d) = prompt_tokens(%d) + completion_tokens(%d)", total, prompt, completion)
+
+	// 推理模型的completion_tokens包含不可见的推理token
+	reasoning := reasoningTokens(final)
+
+	content := stream.content()
+	local, err := d.countTokens(content)
+	if err != nil {
+		return errorOutcome(err)
+	}
+	out.set("local_tokens", local)
+	if reasoning > 0 {
+		out.note("其中包含%d个推理token", reasoning)
+	}
+	out.expect(completion-reasoning == local, "completion_tokens(%d)与本地计算的输出token数(%d)一致", completion-reasoning, local)
+
+	log.Printf("StreamUsage检测: prompt=%d, completion=%d, 本地计算=%d", prompt, completion, local)
+
+	return out.conclude("流式用量统计与实际输出一致", "流式用量统计缺失或与实际输出不符")
+}
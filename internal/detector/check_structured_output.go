@@ -0,0 +1,120 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+func init() {
+	Register(structuredOutputCheck{})
+}
+
+// structuredOutputCheck 检查严格模式的结构化输出(response_format: json_schema)
+//
+// 官方API在strict模式下使用受约束解码，输出必然符合schema；
+// 靠提示词模拟的中转很容易返回带代码块标记或缺字段的JSON。
+type structuredOutputCheck struct{}
+
+func (structuredOutputCheck) Name() string { return "structured_output" }
+func (structuredOutputCheck) Description() string {
+	return "检测严格模式结构化输出(json_schema)的内容是否符合schema以及refusal字段格式"
+}
+func (structuredOutputCheck) Weight() int { return 3 }
+
+// countryFactsSchema 测试用的严格模式schema，包含嵌套对象、数组和枚举
+var countryFactsSchema = mustParseSchema(`{
+	"type": "object",
+	"properties": {
+		"country": {"type": "string"},
+		"capital": {"type": "string"},
+		"continent": {"type": "string", "enum": ["Africa", "Asia", "Europe", "North America", "South America", "Oceania", "Antarctica"]},
+		"population_millions": {"type": "number"},
+		"official_languages": {"type": "array", "items": {"type": "string"}},
+		"landmarks": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"city": {"type": "string"}
+				},
+				"required": ["name", "city"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["country", "capital", "continent", "population_millions", "official_languages", "landmarks"],
+	"additionalProperties": false
+}`)
+
+func (structuredOutputCheck) Run(ctx context.Context, d *Detector) Outcome {
+	req := map[string]interface{}{
+		"model":    d.config.Model,
+		"messages": []map[string]string{{"role": "user", "content": "Give me some basic facts about France, including two famous landmarks."}},
+		"response_format": map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "country_facts",
+				"strict": true,
+				"schema": countryFactsSchema,
+			},
+		},
+		"max_tokens": 300,
+	}
+
+	var response map[string]interface{}
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+
+	choice, ok := firstChoice(response)
+	if !ok {
+		return errorOutcome(fmt.Errorf("无法解析响应格式"))
+	}
+	message, _ := choice["message"].(map[string]interface{})
+
+	var out Outcome
+
+	// 官方API的message总是包含refusal字段，值为null或拒绝说明
+	refusal, hasRefusal := message["refusal"]
+	refusalText, isString := refusal.(string)
+	out.expect(hasRefusal && (refusal == nil || isString), "message.refusal字段存在且为null或字符串（实际为%s）", describeField(refusal, hasRefusal))
+	if isString && refusalText != "" {
+		return skipOutcome("模型拒绝了该请求: %s", previewString(refusalText, 80))
+	}
+
+	finishReason := stringField(choice, "finish_reason")
+	if finishReason == "length" {
+		return skipOutcome("输出因长度限制被截断，无法校验schema")
+	}
+
+	content, _ := messageContent(choice)
+	log.Printf("StructuredOutput检测: finish_reason=%s, 内容片段: %s", finishReason, previewString(content, 60))
+
+	// 受约束解码的输出应当是纯JSON，不会带有代码块标记或额外说明
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		out.expect(false, "message.content是纯JSON: %s", previewString(content, 80))
+		return out.conclude("", "结构化输出不是合法JSON")
+	}
+	out.expect(true, "message.content是纯JSON")
+
+	problems := validateSchema(countryFactsSchema, value, "")
+	out.expect(len(problems) == 0, "输出符合严格模式schema")
+	for _, p := range problems {
+		out.note("%s", p)
+	}
+	out.set("schema_violations", len(problems))
+
+	return out.conclude("结构化输出严格符合schema", "结构化输出违反schema，疑似未使用受约束解码")
+}
+
+// describeField 描述字段的取值情况，用于证据展示
+func describeField(value interface{}, present bool) string {
+	if !present {
+		return "缺失"
+	}
+	return jsonType(value)
+}
@@ -0,0 +1,157 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+)
+
+func init() {
+	Register(toolsCheck{})
+}
+
+// toolsCheck 检查函数调用(tools)是否与官方API行为一致
+//
+// 通过tool_choice强制调用指定函数，校验tool_calls的结构和参数，
+// 再把函数结果以tool角色消息发回，确认多轮函数调用能够正常进行。
+type toolsCheck struct{}
+
+func (toolsCheck) Name() string { return "tools" }
+func (toolsCheck) Description() string {
+	return "检测函数调用(tools/tool_choice)的响应结构和多轮调用是否与官方API一致"
+}
+func (toolsCheck) Weight() int { return 3 }
+
+// Timeout 强制调用和回传工具结果的两轮请求依次发送
+func (toolsCheck) Timeout(base time.Duration) time.Duration { return 2 * base }
+
+// toolCallIDPattern 官方API返回的tool_call id格式
+var toolCallIDPattern = regexp.MustCompile(`^call_[A-Za-z0-9]{20,}$`)
+
+// weatherToolName 测试用的函数名
+const weatherToolName = "get_current_weather"
+
+// weatherToolSchema 测试用函数的参数schema
+var weatherToolSchema = mustParseSchema(`{
+	"type": "object",
+	"properties": {
+		"city": {"type": "string", "description": "City name, e.g. Paris"},
+		"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]}
+	},
+	"required": ["city", "unit"],
+	"additionalProperties": false
+}`)
+
+func (toolsCheck) Run(ctx context.Context, d *Detector) Outcome {
+	tools := []map[string]interface{}{{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        weatherToolName,
+			"description": "Get the current weather in a given city",
+			"parameters":  weatherToolSchema,
+		},
+	}}
+	messages := []interface{}{
+		map[string]interface{}{"role": "user", "content": "What's the weather like in Paris right now? Please use celsius."},
+	}
+
+	req := map[string]interface{}{
+		"model":    d.config.Model,
+		"messages": messages,
+		"tools":    tools,
+		"tool_choice": map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": weatherToolName},
+		},
+	}
+
+	var response map[string]interface{}
+	if err := d.makeRequest(ctx, req, &response); err != nil {
+		return errorOutcome(err)
+	}
+
+	choice, ok := firstChoice(response)
+	if !ok {
+		return errorOutcome(fmt.Errorf("无法解析响应格式"))
+	}
+	message, _ := choice["message"].(map[string]interface{})
+
+	var out Outcome
+
+	// 强制指定函数时官方API返回的finish_reason为stop，自动选择函数时为tool_calls
+	finishReason := stringField(choice, "finish_reason")
+	out.set("finish_reason", finishReason)
+	switch finishReason {
+	case "tool_calls":
+		out.expect(true, "finish_reason为tool_calls")
+	case "stop":
+		out.expect(true, "finish_reason为stop（官方API在tool_choice指定函数时的行为）")
+	default:
+		out.expect(false, "finish_reason应为tool_calls，实际为%q", finishReason)
+	}
+
+	toolCalls, _ := message["tool_calls"].([]interface{})
+	if !out.expect(len(toolCalls) > 0, "message.tool_calls包含%d个函数调用", len(toolCalls)) {
+		return out.conclude("", "未返回函数调用")
+	}
+
+	for i, item := range toolCalls {
+		call, _ := item.(map[string]interface{})
+		fn, _ := call["function"].(map[string]interface{})
+		id := stringField(call, "id")
+		prefix := fmt.Sprintf("tool_calls[%d]", i)
+
+		out.expect(toolCallIDPattern.MatchString(id), "%s.id格式为call_xxx（实际为%q）", prefix, id)
+		out.expect(stringField(call, "type") == "function", "%s.type为function", prefix)
+		out.expect(stringField(fn, "name") == weatherToolName, "%s.function.name为%s（实际为%q）", prefix, weatherToolName, stringField(fn, "name"))
+
+		// 官方API的arguments是JSON字符串而不是对象
+		rawArgs, isString := fn["arguments"].(string)
+		if !out.expect(isString, "%s.function.arguments为字符串", prefix) {
+			continue
+		}
+		var args interface{}
+		if !out.expect(json.Unmarshal([]byte(rawArgs), &args) == nil, "%s.function.arguments是合法的JSON: %s", prefix, previewString(rawArgs, 80)) {
+			continue
+		}
+		problems := validateSchema(weatherToolSchema, args, "arguments")
+		out.expect(len(problems) == 0, "%s.function.arguments符合声明的参数schema", prefix)
+		for _, p := range problems {
+			out.note("%s", p)
+		}
+	}
+	out.set("tool_calls", len(toolCalls))
+
+	log.Printf("Tools检测: finish_reason=%s, tool_calls=%d", finishReason, len(toolCalls))
+
+	// 第二轮：返回函数结果，确认tool角色消息被接受
+	followUp := append(messages, message)
+	for _, item := range toolCalls {
+		call, _ := item.(map[string]interface{})
+		followUp = append(followUp, map[string]interface{}{
+			"role":         "tool",
+			"tool_call_id": stringField(call, "id"),
+			"content":      `{"city": "Paris", "temperature": 18, "unit": "celsius", "condition": "sunny"}`,
+		})
+	}
+	req = map[string]interface{}{
+		"model":    d.config.Model,
+		"messages": followUp,
+		"tools":    tools,
+	}
+
+	var second map[string]interface{}
+	if err := d.makeRequest(ctx, req, &second); err != nil {
+		out.expect(false, "携带tool角色消息的第二轮请求失败: %v", err)
+		return out.conclude("", "函数调用与官方API不一致")
+	}
+	secondChoice, _ := firstChoice(second)
+	content, _ := messageContent(secondChoice)
+	out.expect(content != "", "第二轮请求被接受并根据函数结果生成了回复: %s", previewString(content, 60))
+	out.expect(stringField(secondChoice, "finish_reason") == "stop", "第二轮回复的finish_reason为stop（实际为%q）", stringField(secondChoice, "finish_reason"))
+
+	return out.conclude("函数调用与官方API一致", "函数调用与官方API不一致")
+}
@@ -2,7 +2,9 @@ package detector
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,121 +12,236 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/tiktoken-go/tokenizer"
 )
 
 // Result 表示一次检测的结果
 type Result struct {
-	Timestamp    time.Time `json:"timestamp"`
-	Endpoint     string    `json:"endpoint"`
-	IsRealAPI    bool      `json:"is_real_api"`
-	MaxTokensOK  bool      `json:"max_tokens_ok"`
-	LogprobsOK   bool      `json:"logprobs_ok"`
-	MultipleOK   bool      `json:"multiple_ok"`
-	StopSequence bool      `json:"stop_sequence_ok"`
-	Error        string    `json:"error,omitempty"`
-	RawResponse  string    `json:"raw_response,omitempty"`
-
-	// Token相关信息
-	LocalTokenCount int `json:"local_token_count,omitempty"` // 本地计算的token数量
-	APITokenCount   int `json:"api_token_count,omitempty"`   // API返回的token数量
-	APITotalTokens  int `json:"api_total_tokens,omitempty"`  // API返回的总token数量（包括输入和输出）
+	ID        string        `json:"id"`
+	TargetID  string        `json:"target_id,omitempty"` // 所属监控目标的ID
+	Timestamp time.Time     `json:"timestamp"`
+	Endpoint  string        `json:"endpoint"`
+	Model     string        `json:"model"`       // 本次检测使用的模型
+	IsRealAPI bool          `json:"is_real_api"` // 结论为genuine时为true
+	Score     float64       `json:"score"`       // 0~100的加权真实性评分
+	Coverage  float64       `json:"coverage"`    // 参与评分的检测项权重占比
+	Verdict   Verdict       `json:"verdict"`     // genuine/suspicious/fake/inconclusive
+	Checks    []CheckResult `json:"checks"`      // 各检测项的结果
+	Error     string        `json:"error,omitempty"`
+
+	// RawResponse 按检测项名称记录的原始请求和响应，开启SaveRawResp时保存
+	RawResponse    map[string][]Exchange `json:"raw_response,omitempty"`
+	HasRawResponse bool                  `json:"has_raw_response"`
+}
+
+// Check 按名称返回某一检测项的结果，不存在时返回nil
+func (r *Result) Check(name string) *CheckResult {
+	for i := range r.Checks {
+		if r.Checks[i].Name == name {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+// WithoutRawResponse 返回不含原始请求记录的结果副本，用于列表接口减小响应体积
+func (r Result) WithoutRawResponse() Result {
+	r.RawResponse = nil
+	return r
 }
 
 // Config 表示检测器的配置
 type Config struct {
-	Endpoint    string `json:"endpoint"`          // OpenAI兼容API的端点URL
-	APIKey      string `json:"api_key"`           // API访问密钥
-	Model       string `json:"model"`             // 使用的模型名称
-	Interval    int    `json:"interval"`          // 自动检测间隔（分钟），0表示不自动检测
-	MaxHistory  int    `json:"max_history"`       // 保存的最大历史记录数
-	SaveRawResp bool   `json:"save_raw_response"` // 是否保存原始响应
+	TargetID    string `json:"target_id,omitempty"` // 所属监控目标的ID
+	Endpoint    string `json:"endpoint"`            // OpenAI兼容API的端点URL
+	APIKey      string `json:"api_key"`             // API访问密钥
+	Model       string `json:"model"`               // 使用的模型名称
+	Interval    int    `json:"interval"`            // 自动检测间隔（分钟），0表示不自动检测
+	MaxHistory  int    `json:"max_history"`         // 保存的最大历史记录数
+	MaxAgeDays  int    `json:"max_age_days"`        // 历史记录的保留天数，0表示不按时间清理
+	SaveRawResp bool   `json:"save_raw_response"`   // 是否保存原始响应
+
+	Thresholds Thresholds `json:"thresholds"` // 结论判定阈值
+
+	Encoding string `json:"encoding"` // 本地计算token使用的编码（如o200k_base），为空时根据模型自动选择
+
+	Concurrency  int `json:"concurrency"`   // 同时运行的检测项数量上限
+	CheckTimeout int `json:"check_timeout"` // 单个检测项的默认超时时间（秒）
+}
+
+// 默认的检测执行参数
+const (
+	DefaultConcurrency  = 4
+	DefaultCheckTimeout = 60
+)
+
+// withDefaults 为未设置的配置项填充默认值
+func (c Config) withDefaults() Config {
+	// 设置默认的历史记录数
+	if c.MaxHistory <= 0 {
+		c.MaxHistory = 100
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultConcurrency
+	}
+	if c.CheckTimeout <= 0 {
+		c.CheckTimeout = DefaultCheckTimeout
+	}
+	c.Thresholds = c.Thresholds.withDefaults()
+	return c
+}
+
+// UnmarshalJSON 解析配置，JSON中缺失的阈值字段使用默认值
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	p := plain{Thresholds: DefaultThresholds()}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*c = Config(p)
+	return nil
+}
+
+// retention 返回历史记录的保留策略
+func (c Config) retention() Retention {
+	return Retention{
+		MaxCount: c.MaxHistory,
+		MaxAge:   time.Duration(c.MaxAgeDays) * 24 * time.Hour,
+	}
 }
 
 // Detector 表示API检测器
 type Detector struct {
 	config     Config
-	results    []Result
-	mu         sync.RWMutex // 保护并发访问results
+	store      Store        // 检测结果历史
+	mu         sync.RWMutex // 保护并发访问config
 	httpClient *http.Client
 }
 
-// NewDetector 创建一个新的检测器实例
+// NewDetector 创建一个使用内存存储的检测器实例
 func NewDetector(config Config) *Detector {
-	// 设置默认的历史记录数
-	if config.MaxHistory <= 0 {
-		config.MaxHistory = 100
-	}
+	return NewDetectorWithStore(config, NewMemoryStore())
+}
 
-	return &Detector{
-		config:     config,
-		results:    make([]Result, 0, config.MaxHistory), // 预分配容量
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+// NewDetectorWithStore 创建一个使用指定存储后端的检测器实例
+//
+// 创建时会按保留策略清理一次存储中已有的历史结果。
+func NewDetectorWithStore(config Config, store Store) *Detector {
+	config = config.withDefaults()
+
+	d := &Detector{
+		config: config,
+		store:  store,
+		// 不设置整体超时，由每个检测项的context控制截止时间
+		httpClient: &http.Client{},
 	}
+	d.pruneResults()
+	return d
 }
 
 // GetResults 返回检测结果历史
 func (d *Detector) GetResults() []Result {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.results
+	results, err := d.store.List()
+	if err != nil {
+		log.Printf("读取检测结果失败: %v", err)
+		return nil
+	}
+	return results
 }
 
 // GetLatestResult 返回最新的检测结果
 func (d *Detector) GetLatestResult() *Result {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	if len(d.results) == 0 {
+	results := d.GetResults()
+	if len(results) == 0 {
 		return nil
 	}
 
-	result := d.results[len(d.results)-1]
+	result := results[len(results)-1]
 	return &result
 }
 
+// GetResult 按ID返回检测结果，不存在时返回nil
+func (d *Detector) GetResult(id string) *Result {
+	result, err := d.store.Get(id)
+	if err != nil {
+		log.Printf("读取检测结果失败: %v", err)
+		return nil
+	}
+	return result
+}
+
 // DetectOnce 执行一次完整的API检测
-func (d *Detector) DetectOnce() Result {
+//
+// 各检测项相互独立，按配置的并发数同时运行，每个检测项有各自的截止时间；
+// ctx被取消时尚未完成的检测项会立即返回。检测期间更新配置不影响本次检测。
+func (d *Detector) DetectOnce(ctx context.Context) Result {
+	run := d.snapshot()
+	config := run.config
 	result := Result{
+		ID:        newResultID(),
+		TargetID:  config.TargetID,
+		Model:     config.Model,
 		Timestamp: time.Now(),
-		Endpoint:  d.config.Endpoint,
+		Endpoint:  config.Endpoint,
 		IsRealAPI: false,
 	}
 
-	// 按顺序运行所有检测
-	maxTokensOK, localTokens, apiTokens, totalTokens, maxTokensErr := d.checkMaxTokens()
-	logprobsOK, logprobsErr := d.checkLogprobs()
-	multipleOK, multipleErr := d.checkMultipleResponses()
-	stopOK, stopErr := d.checkStopSequence()
+	checks := Checks()
+	result.Checks = make([]CheckResult, len(checks))
+	transcripts := make([]*transcript, len(checks))
+
+	// 使用信号量限制并发数，结果按检测项顺序写入
+	sem := make(chan struct{}, config.Concurrency)
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				result.Checks[i] = newCheckResult(check, cancelledOutcome(ctx.Err()), 0)
+				return
+			}
 
-	// 设置检测结果
-	result.MaxTokensOK = maxTokensOK
-	result.LogprobsOK = logprobsOK
-	result.MultipleOK = multipleOK
-	result.StopSequence = stopOK
+			// 开启保存原始响应时，记录该检测项发出的所有请求
+			checkCtx := ctx
+			if config.SaveRawResp {
+				checkCtx, transcripts[i] = withTranscript(ctx)
+			}
+			result.Checks[i] = run.runCheck(checkCtx, check, checkTimeout(check, config))
+		}(i, check)
+	}
+	wg.Wait()
 
-	// 设置Token信息
-	result.LocalTokenCount = localTokens
-	result.APITokenCount = apiTokens
-	result.APITotalTokens = totalTokens
+	for i, t := range transcripts {
+		if t == nil {
+			continue
+		}
+		if exchanges := t.list(); len(exchanges) > 0 {
+			if result.RawResponse == nil {
+				result.RawResponse = make(map[string][]Exchange)
+			}
+			result.RawResponse[checks[i].Name()] = exchanges
+		}
+	}
+	result.HasRawResponse = len(result.RawResponse) > 0
 
-	// 如果所有检测都通过，则认为是真实API
-	result.IsRealAPI = maxTokensOK && logprobsOK && multipleOK && stopOK
+	// 根据各检测项权重计算评分并给出结论
+	result.Score, result.Coverage = Score(result.Checks)
+	result.Verdict = config.Thresholds.Judge(result.Score, result.Coverage)
+	result.IsRealAPI = result.Verdict == VerdictGenuine
 
 	// 收集错误信息
 	errorMsgs := []string{}
-	if maxTokensErr != nil {
-		errorMsgs = append(errorMsgs, fmt.Sprintf("Max tokens测试错误: %v", maxTokensErr))
-	}
-	if logprobsErr != nil {
-		errorMsgs = append(errorMsgs, fmt.Sprintf("Logprobs测试错误: %v", logprobsErr))
-	}
-	if multipleErr != nil {
-		errorMsgs = append(errorMsgs, fmt.Sprintf("Multiple测试错误: %v", multipleErr))
-	}
-	if stopErr != nil {
-		errorMsgs = append(errorMsgs, fmt.Sprintf("Stop sequence测试错误: %v", stopErr))
+	for _, cr := range result.Checks {
+		switch cr.Status {
+		case StatusError:
+			errorMsgs = append(errorMsgs, fmt.Sprintf("%s测试错误: %s", cr.Name, cr.Message))
+		case StatusTimeout:
+			errorMsgs = append(errorMsgs, fmt.Sprintf("%s测试超时: %s", cr.Name, cr.Message))
+		}
 	}
 
 	// 合并错误信息
@@ -138,239 +255,294 @@ func (d *Detector) DetectOnce() Result {
 	return result
 }
 
-// saveResult 保存检测结果到历史记录
-func (d *Detector) saveResult(result Result) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	// 添加结果
-	d.results = append(d.results, result)
-
-	// 如果超过最大历史记录数，删除最早的记录
-	if len(d.results) > d.config.MaxHistory {
-		d.results = d.results[len(d.results)-d.config.MaxHistory:]
+// DetectModels 依次使用每个模型执行一次完整检测，结果均保存到同一存储
+//
+// 按顺序而不是并发执行，避免同时向同一端点发送过多请求。
+func (d *Detector) DetectModels(ctx context.Context, models []string) []Result {
+	config := d.Config()
+	results := make([]Result, 0, len(models))
+	for _, model := range models {
+		if ctx.Err() != nil {
+			break
+		}
+		if model == config.Model {
+			results = append(results, d.DetectOnce(ctx))
+			continue
+		}
+		results = append(results, d.forModel(model).DetectOnce(ctx))
 	}
+	return results
 }
 
-// checkMaxTokens 检查max_tokens参数是否生效
-func (d *Detector) checkMaxTokens() (bool, int, int, int, error) {
-	// 构造请求，限制最大token数为10
-	content := "# 人工智能的历史、现状与未来发展趋势\n\n人工智能（Artificial Intelligence，简称AI）"
-	maxTokens := 10
+// forModel 返回使用指定模型、与当前检测器共享存储和HTTP客户端的检测器
+func (d *Detector) forModel(model string) *Detector {
+	s := d.snapshot()
+	s.config.Model = model
+	return s
+}
 
-	req := map[string]interface{}{
-		"model":      d.config.Model,
-		"messages":   []map[string]string{{"role": "user", "content": content}},
-		"max_tokens": maxTokens,
+// snapshot 返回当前配置的快照检测器，与当前检测器共享存储和HTTP客户端
+//
+// 检测项并发读取快照的config，不受检测期间UpdateConfig的影响，
+// 保证同一次检测的所有请求使用相同的端点、密钥和模型。
+func (d *Detector) snapshot() *Detector {
+	return &Detector{
+		config:     d.Config(),
+		store:      d.store,
+		httpClient: d.httpClient,
 	}
+}
 
-	// 计算本地token数
-	localTokenCount, err := countTokens(content)
-	if err != nil {
-		log.Printf("计算本地token失败: %v", err)
-		localTokenCount = -1
+// runCheck 在独立的截止时间内运行单个检测项
+//
+// 即使检测项没有及时响应ctx，到达截止时间后也会立即返回超时结果，
+// 不会阻塞整个检测流程。
+func (d *Detector) runCheck(ctx context.Context, check Check, timeout time.Duration) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errorOutcome(fmt.Errorf("检测项panic: %v", r))
+			}
+		}()
+		done <- check.Run(ctx, d)
+	}()
+
+	var outcome Outcome
+	select {
+	case outcome = <-done:
+		// 检测项因截止时间到达而失败时，统一记为超时
+		if outcome.Status == StatusError && ctx.Err() != nil {
+			outcome = cancelledOutcome(ctx.Err())
+		}
+	case <-ctx.Done():
+		outcome = cancelledOutcome(ctx.Err())
 	}
 
-	var response map[string]interface{}
-	err = d.makeRequest(req, &response)
-	if err != nil {
-		return false, localTokenCount, 0, 0, err
+	if outcome.Status == "" {
+		outcome.Status = StatusError
+		outcome.Message = "检测项未返回状态"
+	}
+	if outcome.Status == StatusTimeout && outcome.Message == "" {
+		outcome.Message = fmt.Sprintf("检测超过%s未完成", timeout)
 	}
 
-	// 获取API返回的token数量
-	apiTokenCount := 0
-	apiTotalTokens := 0
-	var returnedContent string
+	return newCheckResult(check, outcome, time.Since(start))
+}
 
-	if usage, ok := response["usage"].(map[string]interface{}); ok {
-		if ct, ok := usage["completion_tokens"].(float64); ok {
-			apiTokenCount = int(ct)
-		}
-		if tt, ok := usage["total_tokens"].(float64); ok {
-			apiTotalTokens = int(tt)
-		}
+// newCheckResult 使用检测项的元信息包装检测结果
+func newCheckResult(check Check, outcome Outcome, elapsed time.Duration) CheckResult {
+	return CheckResult{
+		Name:        check.Name(),
+		Description: check.Description(),
+		Weight:      check.Weight(),
+		Outcome:     outcome,
+		DurationMs:  elapsed.Milliseconds(),
 	}
+}
 
-	if choices, ok := response["choices"].([]interface{}); ok && len(choices) > 0 {
-		if choice, ok := choices[0].(map[string]interface{}); ok {
-			if message, ok := choice["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"].(string); ok {
-					returnedContent = content
-				}
-			}
-		}
+// cancelledOutcome 将context的错误转换为检测结果
+func cancelledOutcome(err error) Outcome {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Outcome{Status: StatusTimeout}
 	}
-
-	// 简洁的日志输出
-	log.Printf("MaxTokens检测: 目标限制=%d, 本地计算=%d, API返回=%d, 总tokens=%d",
-		maxTokens, localTokenCount, apiTokenCount, apiTotalTokens)
-	log.Printf("MaxTokens检测: 返回内容=%q", returnedContent)
-
-	// 检查返回的token数是否符合限制
-	// 有些API会精确遵循max_tokens，有些则会返回略少一些token
-	return apiTokenCount <= maxTokens, localTokenCount, apiTokenCount, apiTotalTokens, nil
+	return Outcome{Status: StatusError, Message: "检测已取消"}
 }
 
-// checkLogprobs 检查logprobs参数是否生效
-func (d *Detector) checkLogprobs() (bool, error) {
-	// 构造请求，要求返回logprobs
-	req := map[string]interface{}{
-		"model":        d.config.Model,
-		"messages":     []map[string]string{{"role": "user", "content": "What is the capital of France?"}},
-		"logprobs":     true,
-		"top_logprobs": 5,
+// checkTimeout 返回检测项的超时时间，检测项可通过实现TimeoutCheck自定义
+func checkTimeout(check Check, config Config) time.Duration {
+	base := time.Duration(config.CheckTimeout) * time.Second
+	if tc, ok := check.(TimeoutCheck); ok {
+		if t := tc.Timeout(base); t > 0 {
+			return t
+		}
 	}
+	return base
+}
 
-	var response map[string]interface{}
-	err := d.makeRequest(req, &response)
-	if err != nil {
-		return false, err
+// saveResult 保存检测结果到历史记录
+func (d *Detector) saveResult(result Result) {
+	if err := d.store.Save(result); err != nil {
+		log.Printf("保存检测结果失败: %v", err)
+		return
 	}
 
-	// 检查响应中是否包含logprobs
-	hasLogprobs := false
-	if choices, ok := response["choices"].([]interface{}); ok && len(choices) > 0 {
-		if choice, ok := choices[0].(map[string]interface{}); ok {
-			_, hasLogprobs = choice["logprobs"].(map[string]interface{})
-
-			// 简洁的日志输出
-			log.Printf("Logprobs检测: 请求logprobs=true, 响应包含logprobs=%v", hasLogprobs)
+	// 删除超过最大数量或保留时间的记录
+	d.pruneResults()
+}
 
-			return hasLogprobs, nil
-		}
+// pruneResults 按配置的保留策略清理历史记录
+func (d *Detector) pruneResults() {
+	if _, err := d.store.Prune(d.Config().retention()); err != nil {
+		log.Printf("清理历史检测结果失败: %v", err)
 	}
+}
 
-	return false, fmt.Errorf("无法解析响应格式")
+// apiResponse 表示一次API请求的原始响应
+type apiResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Elapsed    time.Duration // 从发出请求到读完响应体的耗时
 }
 
-// checkMultipleResponses 检查n参数是否生效
-func (d *Detector) checkMultipleResponses() (bool, error) {
-	// 构造请求多个回答的请求
-	req := map[string]interface{}{
-		"model":       d.config.Model,
-		"messages":    []map[string]string{{"role": "user", "content": "Tell me a short joke"}},
-		"n":           3,   // 请求3个不同的回答
-		"temperature": 1.0, // 高温度增加多样性
+// errorObject 返回错误响应体中的error对象
+func (r *apiResponse) errorObject() (map[string]interface{}, bool) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		return nil, false
 	}
+	obj, ok := body["error"].(map[string]interface{})
+	return obj, ok
+}
 
-	var response map[string]interface{}
-	err := d.makeRequest(req, &response)
-	if err != nil {
-		return false, err
+// decode 要求响应状态码为200，并将响应体反序列化到v
+func (r *apiResponse) decode(v interface{}) error {
+	// 检查HTTP状态码
+	if r.StatusCode != http.StatusOK {
+		return &statusError{StatusCode: r.StatusCode, Body: string(r.Body)}
 	}
 
-	// 检查是否返回了多个回答
-	n := 0
-	if choices, ok := response["choices"].([]interface{}); ok {
-		n = len(choices)
-
-		// 简洁的日志输出
-		log.Printf("Multiple检测: 请求n=3, 实际返回n=%d", n)
-
-		return n > 1, nil
+	// 反序列化响应体
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("反序列化响应体失败: %w, 响应体: %s", err, truncateString(string(r.Body), 500))
 	}
 
-	return false, fmt.Errorf("无法解析响应格式")
+	return nil
 }
 
-// checkStopSequence 检查stop参数是否生效
-func (d *Detector) checkStopSequence() (bool, error) {
-	// 定义一个特殊的停止序列
-	stopSeq := "THE_END"
+// statusError API返回了非200状态码
+type statusError struct {
+	StatusCode int
+	Body       string
+}
 
-	// 构造请求，包含stop参数
-	req := map[string]interface{}{
-		"model":    d.config.Model,
-		"messages": []map[string]string{{"role": "user", "content": "写一个短篇故事，不要包含THE_END这个词。"}},
-		"stop":     []string{stopSeq},
-	}
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API返回非200状态码: %d, 响应体: %s", e.StatusCode, truncateString(e.Body, 500))
+}
+
+// rejected 判断API是否因请求参数而拒绝请求
+//
+// 官方API对聊天模型接受各检测项使用的参数，返回400或422说明端点不支持所检测的功能；
+// 认证失败、限流和5xx等错误与所检测的功能无关。
+func (e *statusError) rejected() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+}
 
-	var response map[string]interface{}
-	err := d.makeRequest(req, &response)
+// sendRequest 向OpenAI API发送请求并返回原始响应，不检查状态码
+//
+// 请求体会按模型调整输出token上限参数，见adaptTokenLimit。
+func (d *Detector) sendRequest(ctx context.Context, reqBody interface{}) (*apiResponse, error) {
+	reqBody = adaptTokenLimit(d.config.Model, reqBody)
+	resp, err := d.postJSON(ctx, reqBody)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-
-	// 检查返回的文本是否不包含停止序列
-	if choices, ok := response["choices"].([]interface{}); ok && len(choices) > 0 {
-		if choice, ok := choices[0].(map[string]interface{}); ok {
-			if message, ok := choice["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"].(string); ok {
-					notContainsStop := !strings.Contains(content, stopSeq)
-
-					// 简洁的日志输出
-					log.Printf("Stop检测: 设置stop=%s, 响应不包含stop=%v", stopSeq, notContainsStop)
-
-					// 截取一小部分内容
-					contentPreview := content
-					if len(contentPreview) > 40 {
-						contentPreview = contentPreview[:40] + "..."
-					}
-					log.Printf("Stop检测: 内容片段: %s", contentPreview)
-
-					return notContainsStop, nil
-				}
-			}
-		}
+	if retry, ok := completionTokensRetry(reqBody, resp.StatusCode, resp.Body); ok {
+		return d.postJSON(ctx, retry)
 	}
-
-	return false, fmt.Errorf("无法解析响应格式")
+	return resp, nil
 }
 
-// makeRequest 向OpenAI API发送请求
-func (d *Detector) makeRequest(reqBody map[string]interface{}, response interface{}) error {
+// postJSON 将请求体序列化为JSON并发送到聊天补全端点
+func (d *Detector) postJSON(ctx context.Context, reqBody interface{}) (*apiResponse, error) {
 	// 序列化请求体
 	reqJSON, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("序列化请求体失败: %w", err)
+		return nil, fmt.Errorf("序列化请求体失败: %w", err)
 	}
 
+	return d.doRequest(ctx, "POST", d.config.Endpoint, reqJSON)
+}
+
+// doRequest 发送HTTP请求并读取完整响应，请求和响应会记录到context中的记录器
+func (d *Detector) doRequest(ctx context.Context, method, url string, reqJSON []byte) (*apiResponse, error) {
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", d.config.Endpoint, bytes.NewBuffer(reqJSON))
+	var body io.Reader
+	if reqJSON != nil {
+		body = bytes.NewReader(reqJSON)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return fmt.Errorf("创建HTTP请求失败: %w", err)
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 
 	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
+	if reqJSON != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.config.APIKey))
 
 	// 发送请求
+	start := time.Now()
+	ex := newExchange(req, reqJSON, start)
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("发送请求失败: %w", err)
+		ex.fail(err)
+		recordExchange(ctx, ex)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应体
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("读取响应体失败: %w", err)
-	}
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API返回非200状态码: %d, 响应体: %s", resp.StatusCode, truncateString(string(body), 500))
-	}
+		ex.fail(err)
+		recordExchange(ctx, ex)
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+
+	// 记录请求和响应
+	truncated := len(respBody) > maxTranscriptBody
+	if truncated {
+		ex.finish(resp, respBody[:maxTranscriptBody], true)
+	} else {
+		ex.finish(resp, respBody, false)
+	}
+	recordExchange(ctx, ex)
+
+	return &apiResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBody,
+		Elapsed:    time.Since(start),
+	}, nil
+}
 
-	// 反序列化响应体
-	if err := json.Unmarshal(body, response); err != nil {
-		return fmt.Errorf("反序列化响应体失败: %w, 响应体: %s", err, truncateString(string(body), 500))
+// makeRequest 向OpenAI API发送请求，要求返回200并将响应体反序列化到response
+func (d *Detector) makeRequest(ctx context.Context, reqBody map[string]interface{}, response interface{}) error {
+	resp, err := d.sendRequest(ctx, reqBody)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return resp.decode(response)
 }
 
 // UpdateConfig 更新检测器的配置而不创建新的检测器实例
 func (d *Detector) UpdateConfig(config Config) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.config = config
+
+	if config.MaxHistory <= 0 {
+		config.MaxHistory = d.config.MaxHistory
+	}
+	d.config = config.withDefaults()
+}
+
+// Config 返回检测器当前的配置
+func (d *Detector) Config() Config {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.config
 }
 
 // CheckEndpointAvailable 检查API端点是否可访问
 func (d *Detector) CheckEndpointAvailable() bool {
-	req, err := http.NewRequest("GET", d.config.Endpoint, nil)
+	req, err := http.NewRequest("GET", d.Config().Endpoint, nil)
 	if err != nil {
 		return false
 	}
@@ -388,21 +560,6 @@ func (d *Detector) CheckEndpointAvailable() bool {
 	return resp.StatusCode != 0
 }
 
-// countTokens 计算文本的token数量
-func countTokens(text string) (int, error) {
-	enc, err := tokenizer.Get(tokenizer.Cl100kBase)
-	if err != nil {
-		return 0, fmt.Errorf("获取tokenizer失败: %w", err)
-	}
-
-	tokens, _, err := enc.Encode(text)
-	if err != nil {
-		return 0, fmt.Errorf("编码文本失败: %w", err)
-	}
-
-	return len(tokens), nil
-}
-
 // 辅助函数：截断字符串
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,163 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// validateSchema 按JSON Schema的常用子集校验数据，返回所有不符合之处
+//
+// 支持type、properties、required、additionalProperties、enum、items、
+// minItems/maxItems、minimum/maximum和anyOf，足以覆盖函数调用和结构化输出
+// 中官方要求的strict模式schema。
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	if path == "" {
+		path = "$"
+	}
+	var problems []string
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if s, ok := sub.(map[string]interface{}); ok && len(validateSchema(s, value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			problems = append(problems, fmt.Sprintf("%s不满足anyOf中的任何一个schema", path))
+		}
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		return append(problems, fmt.Sprintf("%s的类型应为%v，实际为%s", path, t, jsonType(value)))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s的值%v不在枚举%v中", path, value, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, ok := v[name]; !ok {
+					problems = append(problems, fmt.Sprintf("%s缺少必需字段%q", path, name))
+				}
+			}
+		}
+
+		// 按字段名排序，保证输出稳定
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub, ok := props[key].(map[string]interface{})
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					problems = append(problems, fmt.Sprintf("%s包含schema未声明的字段%q", path, key))
+				}
+				continue
+			}
+			problems = append(problems, validateSchema(sub, v[key], path+"."+key)...)
+		}
+
+	case []interface{}:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			problems = append(problems, fmt.Sprintf("%s至少应有%v项，实际%d项", path, min, len(v)))
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			problems = append(problems, fmt.Sprintf("%s至多应有%v项，实际%d项", path, max, len(v)))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				problems = append(problems, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			problems = append(problems, fmt.Sprintf("%s的值%v小于最小值%v", path, v, min))
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			problems = append(problems, fmt.Sprintf("%s的值%v大于最大值%v", path, v, max))
+		}
+	}
+
+	return problems
+}
+
+// matchesType 判断值是否符合schema中的type（字符串或字符串数组）
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok && matchesSingleType(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchesSingleType 判断值是否符合单个JSON类型
+func matchesSingleType(t string, value interface{}) bool {
+	switch t {
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == t
+	}
+}
+
+// jsonType 返回由encoding/json解码的值对应的JSON类型名
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// mustParseSchema 解析内置的JSON schema，用于包级变量初始化
+//
+// schema以JSON文本定义，保证解析后的类型与响应数据一致（数组为[]interface{}，数值为float64）。
+func mustParseSchema(text string) map[string]interface{} {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &schema); err != nil {
+		panic("detector: 内置schema无效: " + err.Error())
+	}
+	return schema
+}
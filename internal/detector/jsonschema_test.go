@@ -0,0 +1,104 @@
+package detector
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testSchema 覆盖validateSchema支持的全部关键字
+var testSchema = mustParseSchema(`{
+	"type": "object",
+	"properties": {
+		"city": {"type": "string"},
+		"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]},
+		"days": {"type": "integer", "minimum": 1, "maximum": 7},
+		"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 2},
+		"note": {"type": ["string", "null"]},
+		"extra": {"anyOf": [{"type": "number"}, {"type": "boolean"}]}
+	},
+	"required": ["city", "unit"],
+	"additionalProperties": false
+}`)
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{
+			name:  "符合schema",
+			value: `{"city": "Paris", "unit": "celsius", "days": 3, "tags": ["a"], "note": null, "extra": true}`,
+		},
+		{
+			name:  "类型不符时不再检查下级",
+			value: `["Paris"]`,
+			want:  []string{"$的类型应为object，实际为array"},
+		},
+		{
+			name:  "缺少必需字段",
+			value: `{"city": "Paris"}`,
+			want:  []string{`$缺少必需字段"unit"`},
+		},
+		{
+			name:  "未声明的字段按名称排序",
+			value: `{"city": "Paris", "unit": "celsius", "zone": 1, "country": "FR"}`,
+			want: []string{
+				`$包含schema未声明的字段"country"`,
+				`$包含schema未声明的字段"zone"`,
+			},
+		},
+		{
+			name:  "枚举",
+			value: `{"city": "Paris", "unit": "kelvin"}`,
+			want:  []string{"$.unit的值kelvin不在枚举[celsius fahrenheit]中"},
+		},
+		{
+			name:  "整数",
+			value: `{"city": "Paris", "unit": "celsius", "days": 1.5}`,
+			want:  []string{"$.days的类型应为integer，实际为number"},
+		},
+		{
+			name:  "超出最大值",
+			value: `{"city": "Paris", "unit": "celsius", "days": 8}`,
+			want:  []string{"$.days的值8大于最大值7"},
+		},
+		{
+			name:  "数组项数和元素类型",
+			value: `{"city": "Paris", "unit": "celsius", "tags": ["a", 2, "c"]}`,
+			want: []string{
+				"$.tags至多应有2项，实际3项",
+				"$.tags[1]的类型应为string，实际为number",
+			},
+		},
+		{
+			name:  "空数组",
+			value: `{"city": "Paris", "unit": "celsius", "tags": []}`,
+			want:  []string{"$.tags至少应有1项，实际0项"},
+		},
+		{
+			name:  "多个可选类型",
+			value: `{"city": "Paris", "unit": "celsius", "note": 1}`,
+			want:  []string{"$.note的类型应为[string null]，实际为number"},
+		},
+		{
+			name:  "anyOf",
+			value: `{"city": "Paris", "unit": "celsius", "extra": "x"}`,
+			want:  []string{"$.extra不满足anyOf中的任何一个schema"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("解析测试数据失败: %v", err)
+			}
+			got := validateSchema(testSchema, value, "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateSchema() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
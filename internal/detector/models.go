@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// chatModelPrefixes 可用于聊天补全的模型名前缀
+var chatModelPrefixes = []string{"gpt-", "chatgpt-", "o1", "o3", "o4"}
+
+// nonChatModelMarkers 模型名中包含这些片段时不是聊天模型（嵌入、语音、图像等）
+var nonChatModelMarkers = []string{
+	"embedding", "tts", "whisper", "dall-e", "audio", "realtime", "transcribe",
+	"search", "image", "moderation", "instruct",
+}
+
+// IsChatModel 判断模型是否为可用于聊天补全的模型
+func IsChatModel(model string) bool {
+	name := normalizeModelName(model)
+	for _, marker := range nonChatModelMarkers {
+		if strings.Contains(name, marker) {
+			return false
+		}
+	}
+	for _, prefix := range chatModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelsURL 根据聊天补全端点推导模型列表端点
+//
+// 例如https://api.openai.com/v1/chat/completions对应https://api.openai.com/v1/models。
+func ModelsURL(endpoint string) string {
+	trimmed := strings.TrimRight(endpoint, "/")
+	if strings.HasSuffix(trimmed, "/chat/completions") {
+		return strings.TrimSuffix(trimmed, "/chat/completions") + "/models"
+	}
+
+	// 无法识别路径时使用官方的默认路径
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return trimmed + "/models"
+	}
+	u.Path = "/v1/models"
+	u.RawQuery = ""
+	return u.String()
+}
+
+// fetchModels 请求模型列表端点并返回原始响应
+func (d *Detector) fetchModels(ctx context.Context) (*apiResponse, error) {
+	return d.doRequest(ctx, "GET", ModelsURL(d.config.Endpoint), nil)
+}
+
+// ListModels 返回端点提供的聊天模型（按名称排序）
+func (d *Detector) ListModels(ctx context.Context) ([]string, error) {
+	resp, err := d.snapshot().fetchModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := resp.decode(&list); err != nil {
+		return nil, fmt.Errorf("获取模型列表失败: %w", err)
+	}
+
+	var models []string
+	for _, m := range list.Data {
+		if IsChatModel(m.ID) {
+			models = append(models, m.ID)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
@@ -0,0 +1,105 @@
+package detector
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// reasoningModelPrefixes 只接受max_completion_tokens的推理模型
+var reasoningModelPrefixes = []string{"o1", "o3", "o4", "gpt-5"}
+
+// reasoningTokenHeadroom 推理模型在可见输出之外为推理token预留的上限
+//
+// 推理token计入max_completion_tokens，不预留时较小的上限会被推理全部用完，
+// 可见输出为空。
+const reasoningTokenHeadroom = 1024
+
+// isReasoningModel 判断模型是否为只接受max_completion_tokens的推理模型
+func isReasoningModel(model string) bool {
+	name := normalizeModelName(model)
+	// gpt-5-chat-latest等聊天版本不是推理模型
+	if strings.Contains(name, "-chat") {
+		return false
+	}
+	for _, prefix := range reasoningModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenLimitParam 返回模型使用的输出token上限参数名
+func tokenLimitParam(model string) string {
+	if isReasoningModel(model) {
+		return "max_completion_tokens"
+	}
+	return "max_tokens"
+}
+
+// adaptTokenLimit 按模型调整请求体中的输出token上限
+//
+// 检测项统一用max_tokens表示可见输出的上限；推理模型不接受该参数，改为
+// max_completion_tokens并额外预留推理token。已显式使用max_completion_tokens
+// 的请求（如max_tokens检测）保持不变。返回的是副本，不修改原请求体。
+func adaptTokenLimit(model string, reqBody interface{}) interface{} {
+	req, ok := reqBody.(map[string]interface{})
+	if !ok || !isReasoningModel(model) {
+		return reqBody
+	}
+	limit, ok := req["max_tokens"].(int)
+	if !ok {
+		return reqBody
+	}
+	return withCompletionTokens(req, limit+reasoningTokenHeadroom)
+}
+
+// completionTokensRetry 模型以400拒绝max_tokens（未识别的推理模型）时，
+// 返回改用max_completion_tokens的请求体
+func completionTokensRetry(reqBody interface{}, status int, respBody []byte) (map[string]interface{}, bool) {
+	req, ok := reqBody.(map[string]interface{})
+	if !ok || status != http.StatusBadRequest {
+		return nil, false
+	}
+	limit, ok := req["max_tokens"].(int)
+	if !ok {
+		return nil, false
+	}
+
+	var body map[string]interface{}
+	if json.Unmarshal(respBody, &body) != nil {
+		return nil, false
+	}
+	errObj, _ := body["error"].(map[string]interface{})
+	if stringField(errObj, "param") != "max_tokens" {
+		return nil, false
+	}
+	// 只在参数不被支持时重试，max_tokens超出上限等错误应原样返回
+	if stringField(errObj, "code") != "unsupported_parameter" && !strings.Contains(stringField(errObj, "message"), "max_completion_tokens") {
+		return nil, false
+	}
+
+	log.Printf("模型%v不支持max_tokens，改用max_completion_tokens重试", req["model"])
+	return withCompletionTokens(req, limit), true
+}
+
+// withCompletionTokens 返回将max_tokens替换为max_completion_tokens的请求体副本
+func withCompletionTokens(req map[string]interface{}, limit int) map[string]interface{} {
+	body := make(map[string]interface{}, len(req))
+	for k, v := range req {
+		body[k] = v
+	}
+	delete(body, "max_tokens")
+	body["max_completion_tokens"] = limit
+	return body
+}
+
+// skipForReasoning 推理模型不支持检测所依赖的参数时返回跳过结果
+func skipForReasoning(d *Detector, params string) (Outcome, bool) {
+	if isReasoningModel(d.config.Model) {
+		return skipOutcome("推理模型%s不支持%s", d.config.Model, params), true
+	}
+	return Outcome{}, false
+}
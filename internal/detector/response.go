@@ -0,0 +1,74 @@
+package detector
+
+// 本文件提供解析OpenAI兼容响应（map形式）的辅助函数
+
+// choicesOf 返回响应中的choices数组
+func choicesOf(response map[string]interface{}) []map[string]interface{} {
+	raw, ok := response["choices"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	choices := make([]map[string]interface{}, 0, len(raw))
+	for _, item := range raw {
+		if choice, ok := item.(map[string]interface{}); ok {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// firstChoice 返回响应中的第一个choice
+func firstChoice(response map[string]interface{}) (map[string]interface{}, bool) {
+	choices := choicesOf(response)
+	if len(choices) == 0 {
+		return nil, false
+	}
+	return choices[0], true
+}
+
+// messageContent 返回choice中message的文本内容
+func messageContent(choice map[string]interface{}) (string, bool) {
+	message, ok := choice["message"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	content, ok := message["content"].(string)
+	return content, ok
+}
+
+// usageInt 返回usage中指定字段的整数值，字段不存在时返回-1
+func usageInt(response map[string]interface{}, key string) int {
+	usage, ok := response["usage"].(map[string]interface{})
+	if !ok {
+		return -1
+	}
+	v, ok := usage[key].(float64)
+	if !ok {
+		return -1
+	}
+	return int(v)
+}
+
+// reasoningTokens 返回usage中推理模型不可见的推理token数，不存在时返回0
+func reasoningTokens(response map[string]interface{}) int {
+	usage, _ := response["usage"].(map[string]interface{})
+	details, _ := usage["completion_tokens_details"].(map[string]interface{})
+	v, _ := details["reasoning_tokens"].(float64)
+	return int(v)
+}
+
+// stringField 返回map中指定字段的字符串值
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// previewString 截取内容片段用于日志和证据展示
+func previewString(s string, maxLen int) string {
+	r := []rune(s)
+	if len(r) <= maxLen {
+		return s
+	}
+	return string(r[:maxLen]) + "..."
+}
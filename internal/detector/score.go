@@ -0,0 +1,119 @@
+package detector
+
+import (
+	"fmt"
+	"math"
+)
+
+// Verdict 表示根据加权评分得出的结论
+type Verdict string
+
+const (
+	VerdictGenuine      Verdict = "genuine"      // 真实API
+	VerdictSuspicious   Verdict = "suspicious"   // 可疑，可能掺水
+	VerdictFake         Verdict = "fake"         // 中转/逆向API
+	VerdictInconclusive Verdict = "inconclusive" // 有效检测项太少，无法判断
+)
+
+// Thresholds 表示结论判定的阈值配置
+type Thresholds struct {
+	Genuine     float64 `json:"genuine"`      // 评分不低于该值判定为真实API
+	Suspicious  float64 `json:"suspicious"`   // 评分不低于该值判定为可疑，否则判定为中转API
+	MinCoverage float64 `json:"min_coverage"` // 有效检测项（通过或未通过）权重占比低于该值时结论为无法判断，取值0~1
+}
+
+// 默认的结论判定阈值
+const (
+	DefaultGenuineThreshold    = 80
+	DefaultSuspiciousThreshold = 50
+	DefaultMinCoverage         = 0.5
+)
+
+// DefaultThresholds 返回默认的结论判定阈值
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		Genuine:     DefaultGenuineThreshold,
+		Suspicious:  DefaultSuspiciousThreshold,
+		MinCoverage: DefaultMinCoverage,
+	}
+}
+
+// withDefaults 为未设置的阈值填充默认值
+//
+// 全零的阈值（例如NewDetector(Config{})）和负数视为未设置；单个字段为0是有效的
+// （例如最低覆盖率为0表示只要有检测项参与评分就给出结论）。
+// 从JSON解析配置时缺失的字段由Config.UnmarshalJSON填充默认值。
+func (t Thresholds) withDefaults() Thresholds {
+	if t == (Thresholds{}) {
+		return DefaultThresholds()
+	}
+	if t.Genuine < 0 {
+		t.Genuine = DefaultGenuineThreshold
+	}
+	if t.Suspicious < 0 {
+		t.Suspicious = DefaultSuspiciousThreshold
+	}
+	if t.MinCoverage < 0 {
+		t.MinCoverage = DefaultMinCoverage
+	}
+	return t
+}
+
+// Validate 检查阈值是否在有效范围内
+func (t Thresholds) Validate() error {
+	switch {
+	case t.Genuine <= 0 || t.Genuine > 100:
+		return fmt.Errorf("真实API阈值应大于0且不超过100: %v", t.Genuine)
+	case t.Suspicious < 0 || t.Suspicious > 100:
+		return fmt.Errorf("可疑阈值应在0~100之间: %v", t.Suspicious)
+	case t.Suspicious > t.Genuine:
+		return fmt.Errorf("可疑阈值(%v)不能高于真实API阈值(%v)", t.Suspicious, t.Genuine)
+	case t.MinCoverage < 0 || t.MinCoverage > 1:
+		return fmt.Errorf("最低覆盖率应在0~1之间: %v", t.MinCoverage)
+	}
+	return nil
+}
+
+// Score 根据各检测项的权重计算0~100的真实性评分
+//
+// 只有通过或未通过的检测项参与评分，跳过和出错的检测项不计入；
+// coverage为参与评分的权重占全部权重的比例。
+func Score(checks []CheckResult) (score float64, coverage float64) {
+	var passed, evaluated, total int
+	for _, c := range checks {
+		total += c.Weight
+		switch c.Status {
+		case StatusPass:
+			passed += c.Weight
+			evaluated += c.Weight
+		case StatusFail:
+			evaluated += c.Weight
+		}
+	}
+
+	if total == 0 || evaluated == 0 {
+		return 0, 0
+	}
+
+	score = math.Round(float64(passed)/float64(evaluated)*1000) / 10
+	coverage = float64(evaluated) / float64(total)
+	return score, coverage
+}
+
+// Judge 根据评分、覆盖率和阈值给出结论
+//
+// 没有任何检测项参与评分（覆盖率为0，例如端点不可用）时总是无法判断。
+func (t Thresholds) Judge(score, coverage float64) Verdict {
+	t = t.withDefaults()
+
+	switch {
+	case coverage <= 0 || coverage < t.MinCoverage:
+		return VerdictInconclusive
+	case score >= t.Genuine:
+		return VerdictGenuine
+	case score >= t.Suspicious:
+		return VerdictSuspicious
+	default:
+		return VerdictFake
+	}
+}
@@ -0,0 +1,132 @@
+package detector
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name         string
+		checks       []CheckResult
+		wantScore    float64
+		wantCoverage float64
+	}{
+		{
+			name:   "没有检测项",
+			checks: nil,
+		},
+		{
+			name: "全部通过",
+			checks: []CheckResult{
+				weighted(StatusPass, 3),
+				weighted(StatusPass, 2),
+			},
+			wantScore:    100,
+			wantCoverage: 1,
+		},
+		{
+			name: "按权重计算",
+			checks: []CheckResult{
+				weighted(StatusPass, 3),
+				weighted(StatusFail, 1),
+			},
+			wantScore:    75,
+			wantCoverage: 1,
+		},
+		{
+			name: "跳过和出错的检测项不参与评分",
+			checks: []CheckResult{
+				weighted(StatusPass, 2),
+				weighted(StatusFail, 2),
+				weighted(StatusSkip, 3),
+				weighted(StatusError, 2),
+				weighted(StatusTimeout, 1),
+			},
+			wantScore:    50,
+			wantCoverage: 0.4,
+		},
+		{
+			name: "没有有效检测项",
+			checks: []CheckResult{
+				weighted(StatusError, 3),
+				weighted(StatusSkip, 2),
+			},
+		},
+		{
+			name: "评分保留一位小数",
+			checks: []CheckResult{
+				weighted(StatusPass, 2),
+				weighted(StatusFail, 1),
+			},
+			wantScore:    66.7,
+			wantCoverage: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, coverage := Score(tt.checks)
+			if score != tt.wantScore || coverage != tt.wantCoverage {
+				t.Errorf("Score() = (%v, %v), want (%v, %v)", score, coverage, tt.wantScore, tt.wantCoverage)
+			}
+		})
+	}
+}
+
+func TestJudge(t *testing.T) {
+	defaults := DefaultThresholds()
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		score      float64
+		coverage   float64
+		want       Verdict
+	}{
+		{"达到真实阈值", defaults, 80, 1, VerdictGenuine},
+		{"低于真实阈值", defaults, 79.9, 1, VerdictSuspicious},
+		{"达到可疑阈值", defaults, 50, 1, VerdictSuspicious},
+		{"低于可疑阈值", defaults, 49.9, 1, VerdictFake},
+		{"覆盖率不足", defaults, 100, 0.4, VerdictInconclusive},
+		{"覆盖率恰好达到下限", defaults, 100, 0.5, VerdictGenuine},
+		{"全零阈值使用默认值", Thresholds{}, 60, 1, VerdictSuspicious},
+		{"负数阈值使用默认值", Thresholds{Genuine: -1, Suspicious: 30, MinCoverage: 0.2}, 70, 0.3, VerdictSuspicious},
+		{"最低覆盖率为0时只要有检测项参与评分即给出结论", Thresholds{Genuine: 90, Suspicious: 60, MinCoverage: 0}, 95, 0.1, VerdictGenuine},
+		{"覆盖率为0时总是无法判断", Thresholds{Genuine: 90, Suspicious: 60, MinCoverage: 0}, 0, 0, VerdictInconclusive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.thresholds.Judge(tt.score, tt.coverage); got != tt.want {
+				t.Errorf("Judge(%v, %v) = %q, want %q", tt.score, tt.coverage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholdsValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		wantErr    bool
+	}{
+		{"默认阈值", DefaultThresholds(), false},
+		{"可疑阈值为0", Thresholds{Genuine: 80, Suspicious: 0, MinCoverage: 0.5}, false},
+		{"可疑阈值等于真实阈值", Thresholds{Genuine: 70, Suspicious: 70, MinCoverage: 1}, false},
+		{"全零阈值", Thresholds{}, true},
+		{"真实阈值超过100", Thresholds{Genuine: 101, Suspicious: 50, MinCoverage: 0.5}, true},
+		{"可疑阈值为负数", Thresholds{Genuine: 80, Suspicious: -1, MinCoverage: 0.5}, true},
+		{"可疑阈值高于真实阈值", Thresholds{Genuine: 60, Suspicious: 70, MinCoverage: 0.5}, true},
+		{"最低覆盖率超过1", Thresholds{Genuine: 80, Suspicious: 50, MinCoverage: 1.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.thresholds.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// weighted 返回指定状态和权重的检测项结果
+func weighted(status CheckStatus, weight int) CheckResult {
+	return CheckResult{Outcome: Outcome{Status: status}, Weight: weight}
+}
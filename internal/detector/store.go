@@ -0,0 +1,88 @@
+package detector
+
+import (
+	"sync"
+	"time"
+)
+
+// Store 保存检测结果历史的存储后端
+//
+// 实现需要支持并发访问，List按保存顺序（从旧到新）返回结果。
+type Store interface {
+	Save(result Result) error
+	List() ([]Result, error)
+	Get(id string) (*Result, error)
+	// Prune 按保留策略删除过期的结果，返回删除的数量
+	Prune(retention Retention) (int, error)
+}
+
+// Retention 检测结果的保留策略，零值表示不限制
+type Retention struct {
+	MaxCount int           // 最多保留的结果数量
+	MaxAge   time.Duration // 结果的最长保留时间
+}
+
+// Apply 返回按保留策略应保留的结果，results需按时间从旧到新排列
+func (r Retention) Apply(results []Result, now time.Time) []Result {
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		i := 0
+		for i < len(results) && results[i].Timestamp.Before(cutoff) {
+			i++
+		}
+		results = results[i:]
+	}
+	if r.MaxCount > 0 && len(results) > r.MaxCount {
+		results = results[len(results)-r.MaxCount:]
+	}
+	return results
+}
+
+// MemoryStore 内存中的存储后端，重启后数据会丢失
+type MemoryStore struct {
+	mu      sync.RWMutex
+	results []Result
+}
+
+// NewMemoryStore 创建一个内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Save 保存一条检测结果
+func (s *MemoryStore) Save(result Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, result)
+	return nil
+}
+
+// List 返回所有检测结果
+func (s *MemoryStore) List() ([]Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Result(nil), s.results...), nil
+}
+
+// Get 按ID返回检测结果，不存在时返回nil
+func (s *MemoryStore) Get(id string) (*Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.results {
+		if s.results[i].ID == id {
+			result := s.results[i]
+			return &result, nil
+		}
+	}
+	return nil, nil
+}
+
+// Prune 按保留策略删除过期的结果
+func (s *MemoryStore) Prune(retention Retention) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := retention.Apply(s.results, time.Now())
+	removed := len(s.results) - len(kept)
+	s.results = append([]Result(nil), kept...)
+	return removed, nil
+}
@@ -0,0 +1,66 @@
+package detector
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionApply(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	// 每天一条结果，从旧到新为r1~r5
+	var results []Result
+	for i := 1; i <= 5; i++ {
+		results = append(results, Result{
+			ID:        fmt.Sprintf("r%d", i),
+			Timestamp: now.AddDate(0, 0, i-5),
+		})
+	}
+
+	tests := []struct {
+		name      string
+		retention Retention
+		want      []string
+	}{
+		{"不限制", Retention{}, []string{"r1", "r2", "r3", "r4", "r5"}},
+		{"按数量保留最新的结果", Retention{MaxCount: 2}, []string{"r4", "r5"}},
+		{"数量未超出上限", Retention{MaxCount: 10}, []string{"r1", "r2", "r3", "r4", "r5"}},
+		{"按时间删除过期结果", Retention{MaxAge: 48 * time.Hour}, []string{"r3", "r4", "r5"}},
+		{"同时按时间和数量清理", Retention{MaxCount: 2, MaxAge: 72 * time.Hour}, []string{"r4", "r5"}},
+		{"只有最新的结果未过期", Retention{MaxAge: time.Hour}, []string{"r5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range tt.retention.Apply(results, now) {
+				got = append(got, r.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStorePrune(t *testing.T) {
+	s := NewMemoryStore()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := s.Save(Result{ID: id, Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := s.Prune(Retention{MaxCount: 1})
+	if err != nil || removed != 2 {
+		t.Fatalf("Prune() = (%d, %v), want (2, nil)", removed, err)
+	}
+	results, _ := s.List()
+	if len(results) != 1 || results[0].ID != "c" {
+		t.Errorf("List() = %v, want only c", results)
+	}
+	if r, _ := s.Get("a"); r != nil {
+		t.Errorf("Get(a) = %v, want nil after prune", r)
+	}
+}
@@ -0,0 +1,207 @@
+package detector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// streamChunk 表示SSE流中的一个数据块
+type streamChunk struct {
+	Raw     string                 // data字段的原始内容
+	Data    map[string]interface{} // 解析后的JSON
+	Elapsed time.Duration          // 收到该数据块时距请求发出的时间
+}
+
+// streamResponse 表示一次流式请求的完整结果
+type streamResponse struct {
+	StatusCode  int
+	Header      http.Header
+	Chunks      []streamChunk
+	Done        bool     // 是否收到[DONE]终止标记
+	Problems    []string // SSE帧格式上的问题
+	TotalTime   time.Duration
+	ContentType string
+}
+
+// maxStreamProblems 限制记录的帧格式问题数量，避免异常流刷屏
+const maxStreamProblems = 20
+
+// problem 记录一条帧格式问题
+func (s *streamResponse) problem(format string, args ...interface{}) {
+	if len(s.Problems) < maxStreamProblems {
+		s.Problems = append(s.Problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// streamRequest 以stream模式向API发送请求并按SSE格式解析响应
+//
+// 与makeRequest不同，非200状态码仍会返回错误，但SSE帧格式上的问题
+// 不会导致错误，而是记录在Problems中供检测项判断。
+func (d *Detector) streamRequest(ctx context.Context, reqBody map[string]interface{}) (*streamResponse, error) {
+	body := make(map[string]interface{}, len(reqBody)+1)
+	for k, v := range reqBody {
+		body[k] = v
+	}
+	body["stream"] = true
+
+	// 与sendRequest一样按模型调整输出token上限参数
+	adapted := adaptTokenLimit(d.config.Model, body)
+	stream, err := d.openStream(ctx, adapted)
+	var se *statusError
+	if errors.As(err, &se) {
+		if retry, ok := completionTokensRetry(adapted, se.StatusCode, []byte(se.Body)); ok {
+			return d.openStream(ctx, retry)
+		}
+	}
+	return stream, err
+}
+
+// openStream 发送流式请求并解析SSE响应
+func (d *Detector) openStream(ctx context.Context, body interface{}) (*streamResponse, error) {
+	// 序列化请求体
+	reqJSON, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求体失败: %w", err)
+	}
+
+	// 创建HTTP请求
+	req, err := http.NewRequestWithContext(ctx, "POST", d.config.Endpoint, bytes.NewBuffer(reqJSON))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+
+	// 设置请求头
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.config.APIKey))
+
+	// 发送请求
+	start := time.Now()
+	ex := newExchange(req, reqJSON, start)
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		ex.fail(err)
+		recordExchange(ctx, ex)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 检查HTTP状态码
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		ex.finish(resp, errBody, false)
+		recordExchange(ctx, ex)
+		return nil, &statusError{StatusCode: resp.StatusCode, Body: string(errBody)}
+	}
+
+	// 读取的同时保留原始SSE文本用于记录
+	raw := &limitedBuffer{limit: maxTranscriptBody}
+	defer func() {
+		ex.finish(resp, raw.buf.Bytes(), raw.truncated)
+		recordExchange(ctx, ex)
+	}()
+
+	result := &streamResponse{
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+
+	// 逐行读取SSE事件，空行表示一个事件结束
+	scanner := bufio.NewScanner(io.TeeReader(resp.Body, raw))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var dataLines []string
+	flush := func() {
+		if len(dataLines) == 0 {
+			return
+		}
+		data := strings.Join(dataLines, "\n")
+		dataLines = nil
+		result.handleEvent(data, time.Since(start))
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, ":"):
+			// SSE注释行（常用作心跳），规范允许
+		case strings.HasPrefix(line, "data:"):
+			data := strings.TrimPrefix(line, "data:")
+			if !strings.HasPrefix(data, " ") {
+				result.problem("data字段后缺少空格: %q", truncateString(line, 80))
+			}
+			dataLines = append(dataLines, strings.TrimPrefix(data, " "))
+		default:
+			field := line
+			if i := strings.Index(line, ":"); i >= 0 {
+				field = line[:i]
+			}
+			result.problem("出现官方API不会发送的SSE字段%q: %q", field, truncateString(line, 80))
+		}
+	}
+	if len(dataLines) > 0 {
+		result.problem("最后一个事件缺少结束空行")
+		flush()
+	}
+	result.TotalTime = time.Since(start)
+
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("读取流式响应失败: %w", err)
+	}
+
+	return result, nil
+}
+
+// handleEvent 处理一个完整的SSE事件
+func (s *streamResponse) handleEvent(data string, elapsed time.Duration) {
+	if s.Done {
+		s.problem("[DONE]之后仍收到数据: %q", truncateString(data, 80))
+		return
+	}
+	if data == "[DONE]" {
+		s.Done = true
+		return
+	}
+
+	var chunk map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		s.problem("数据块不是合法的JSON对象: %q", truncateString(data, 80))
+		return
+	}
+	s.Chunks = append(s.Chunks, streamChunk{Raw: data, Data: chunk, Elapsed: elapsed})
+}
+
+// chunkDelta 返回数据块中第一个choice的delta
+func chunkDelta(chunk map[string]interface{}) (map[string]interface{}, bool) {
+	choice, ok := firstChoice(chunk)
+	if !ok {
+		return nil, false
+	}
+	delta, ok := choice["delta"].(map[string]interface{})
+	return delta, ok
+}
+
+// content 拼接所有数据块中的delta内容
+func (s *streamResponse) content() string {
+	var sb strings.Builder
+	for _, chunk := range s.Chunks {
+		if delta, ok := chunkDelta(chunk.Data); ok {
+			if content, ok := delta["content"].(string); ok {
+				sb.WriteString(content)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package detector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// modelEncodings 按模型名前缀映射到对应的tokenizer编码，按顺序匹配
+var modelEncodings = []struct {
+	prefix   string
+	encoding tokenizer.Encoding
+}{
+	{"gpt-4o", tokenizer.O200kBase},
+	{"chatgpt-4o", tokenizer.O200kBase},
+	{"gpt-4.1", tokenizer.O200kBase},
+	{"gpt-4.5", tokenizer.O200kBase},
+	{"gpt-5", tokenizer.O200kBase},
+	{"o1", tokenizer.O200kBase},
+	{"o3", tokenizer.O200kBase},
+	{"o4", tokenizer.O200kBase},
+	{"gpt-4", tokenizer.Cl100kBase},
+	{"gpt-3.5", tokenizer.Cl100kBase},
+	{"gpt-35", tokenizer.Cl100kBase},
+	{"text-embedding-", tokenizer.Cl100kBase},
+}
+
+// defaultEncoding 无法识别模型时使用的编码（新模型均使用o200k_base）
+const defaultEncoding = tokenizer.O200kBase
+
+// EncodingForModel 返回模型对应的tokenizer编码
+//
+// 支持带供应商前缀（如"openai/gpt-4o"）和微调前缀（如"ft:gpt-4o-mini:..."）的模型名。
+func EncodingForModel(model string) tokenizer.Encoding {
+	name := normalizeModelName(model)
+	for _, m := range modelEncodings {
+		if strings.HasPrefix(name, m.prefix) {
+			return m.encoding
+		}
+	}
+	return defaultEncoding
+}
+
+// normalizeModelName 去掉模型名中的供应商前缀和微调前缀并转为小写
+func normalizeModelName(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	name = strings.TrimPrefix(name, "ft:")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// 已创建的tokenizer缓存，避免每次计算都重新加载词表
+var (
+	codecMu    sync.Mutex
+	codecCache = make(map[tokenizer.Encoding]tokenizer.Codec)
+)
+
+// getCodec 返回指定编码的tokenizer，同一编码只会创建一次
+func getCodec(encoding tokenizer.Encoding) (tokenizer.Codec, error) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+
+	if enc, ok := codecCache[encoding]; ok {
+		return enc, nil
+	}
+	enc, err := tokenizer.Get(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("获取tokenizer(%s)失败: %w", encoding, err)
+	}
+	codecCache[encoding] = enc
+	return enc, nil
+}
+
+// ValidateEncoding 检查编码名称是否可用，为空表示根据模型自动选择
+func ValidateEncoding(encoding string) error {
+	if encoding == "" {
+		return nil
+	}
+	_, err := getCodec(tokenizer.Encoding(encoding))
+	return err
+}
+
+// encoding 返回检测器使用的tokenizer编码，配置中指定的编码优先
+func (d *Detector) encoding() tokenizer.Encoding {
+	config := d.Config()
+	if config.Encoding != "" {
+		return tokenizer.Encoding(config.Encoding)
+	}
+	return EncodingForModel(config.Model)
+}
+
+// codec 返回检测器当前模型对应的tokenizer
+func (d *Detector) codec() (tokenizer.Codec, error) {
+	return getCodec(d.encoding())
+}
+
+// encode 将文本编码为token ID
+func (d *Detector) encode(text string) ([]uint, error) {
+	enc, err := d.codec()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, _, err := enc.Encode(text)
+	if err != nil {
+		return nil, fmt.Errorf("编码文本失败: %w", err)
+	}
+
+	return ids, nil
+}
+
+// countTokens 计算文本的token数量
+func (d *Detector) countTokens(text string) (int, error) {
+	ids, err := d.encode(text)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// 聊天格式中每条消息的额外token开销（参考OpenAI cookbook中的计算方法）
+const (
+	tokensPerMessage = 3 // 每条消息的<|start|>{role}\n ... <|end|>\n 包装
+	tokensPerName    = 1 // 消息带有name字段时的额外开销
+	tokensPerReply   = 3 // 每次回复以<|start|>assistant<|message|>开头
+)
+
+// countChatTokens 计算一组聊天消息作为输入时的token数量
+func (d *Detector) countChatTokens(messages []map[string]string) (int, error) {
+	total := tokensPerReply
+	for _, message := range messages {
+		total += tokensPerMessage
+		for key, value := range message {
+			n, err := d.countTokens(value)
+			if err != nil {
+				return 0, err
+			}
+			total += n
+			if key == "name" {
+				total += tokensPerName
+			}
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,139 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exchange 记录检测过程中的一次API请求及其响应
+type Exchange struct {
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     json.RawMessage   `json:"request_body,omitempty"`
+	StatusCode      int               `json:"status_code,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"` // 响应体超过上限被截断
+	StartedAt       time.Time         `json:"started_at"`
+	DurationMs      int64             `json:"duration_ms"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// maxTranscriptBody 单个响应体在记录中保留的最大字节数
+const maxTranscriptBody = 64 * 1024
+
+// transcript 收集一个检测项发出的所有请求，通过context传递给请求函数
+type transcript struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+type transcriptKey struct{}
+
+// withTranscript 返回携带新记录器的context
+func withTranscript(ctx context.Context) (context.Context, *transcript) {
+	t := &transcript{}
+	return context.WithValue(ctx, transcriptKey{}, t), t
+}
+
+// list 返回已记录的请求副本；检测项超时后仍可能在写入，因此需要加锁
+func (t *transcript) list() []Exchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Exchange(nil), t.exchanges...)
+}
+
+// recordExchange 将一次请求记录到context中的记录器，未开启记录时忽略
+func recordExchange(ctx context.Context, ex Exchange) {
+	t, ok := ctx.Value(transcriptKey{}).(*transcript)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.exchanges = append(t.exchanges, ex)
+	t.mu.Unlock()
+}
+
+// newExchange 根据HTTP请求创建记录，API密钥会被隐去
+func newExchange(req *http.Request, body []byte, start time.Time) Exchange {
+	ex := Exchange{
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		RequestHeaders: flattenHeaders(req.Header),
+		StartedAt:      start,
+	}
+	if auth, ok := ex.RequestHeaders["Authorization"]; ok {
+		ex.RequestHeaders["Authorization"] = "Bearer " + RedactKey(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if json.Valid(body) {
+		ex.RequestBody = json.RawMessage(body)
+	}
+	return ex
+}
+
+// finish 记录响应信息
+func (ex *Exchange) finish(resp *http.Response, body []byte, truncated bool) {
+	ex.StatusCode = resp.StatusCode
+	ex.ResponseHeaders = flattenHeaders(resp.Header)
+	ex.ResponseBody = string(body)
+	ex.Truncated = truncated
+	ex.DurationMs = time.Since(ex.StartedAt).Milliseconds()
+}
+
+// fail 记录请求失败的原因
+func (ex *Exchange) fail(err error) {
+	ex.Error = err.Error()
+	ex.DurationMs = time.Since(ex.StartedAt).Milliseconds()
+}
+
+// flattenHeaders 将HTTP头转换为便于展示的map，多个值以逗号连接
+func flattenHeaders(header http.Header) map[string]string {
+	flat := make(map[string]string, len(header))
+	for name, values := range header {
+		flat[name] = strings.Join(values, ", ")
+	}
+	return flat
+}
+
+// RedactKey 隐去API密钥，只保留首尾少量字符用于辨认
+func RedactKey(key string) string {
+	if len(key) <= 12 {
+		return "***"
+	}
+	return key[:3] + "***" + key[len(key)-4:]
+}
+
+// limitedBuffer 只保留前limit个字节的写入缓冲，超出部分被丢弃
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// newResultID 生成检测结果的唯一ID
+func newResultID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
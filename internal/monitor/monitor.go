@@ -0,0 +1,587 @@
+// Package monitor 管理多个监控目标，为每个目标维护独立的检测器和定时任务
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/user/isGPTReal/internal/detector"
+)
+
+var (
+	ErrNotFound      = errors.New("监控目标不存在")
+	ErrDetecting     = errors.New("检测正在进行中")
+	ErrDefaultTarget = errors.New("不能删除默认监控目标")
+)
+
+// maxDiscoveredModels 自动发现模型时每次最多额外检测的模型数量
+const maxDiscoveredModels = 8
+
+// StoreFactory 为指定ID的监控目标创建结果存储
+type StoreFactory func(targetID string) (detector.Store, error)
+
+// Monitor 管理所有监控目标
+type Monitor struct {
+	mu        sync.RWMutex
+	base      detector.Config // 各目标共享的检测参数（阈值、并发数等）
+	entries   map[string]*entry
+	order     []string // 目标的添加顺序
+	cron      *cron.Cron
+	openStore StoreFactory
+	path      string // 保存监控目标的文件，为空时不持久化
+}
+
+// entry 一个监控目标及其运行状态
+type entry struct {
+	target    Target
+	detector  *detector.Detector
+	cronID    cron.EntryID
+	detecting bool
+	startedAt time.Time
+}
+
+// Status 描述监控目标的当前状态和最新结果
+type Status struct {
+	Target    Target           `json:"target"`
+	Detecting bool             `json:"detecting"`
+	StartedAt *time.Time       `json:"started_at,omitempty"` // 正在进行的检测的开始时间
+	Latest    *detector.Result `json:"latest,omitempty"`
+}
+
+// New 创建监控器
+//
+// base中的端点、密钥、模型和检测间隔构成默认监控目标，其余字段作为所有目标
+// 共享的检测参数。path不为空时，从该文件加载并保存其他监控目标。
+func New(base detector.Config, openStore StoreFactory, path string) (*Monitor, error) {
+	m := &Monitor{
+		base:      base,
+		entries:   make(map[string]*entry),
+		cron:      cron.New(),
+		openStore: openStore,
+		path:      path,
+	}
+
+	defaultTarget := Target{
+		ID:       DefaultTargetID,
+		Name:     "默认",
+		Endpoint: base.Endpoint,
+		APIKey:   base.APIKey,
+		Models:   []string{base.Model},
+		Interval: base.Interval,
+	}
+	if err := m.addEntry(defaultTarget); err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		targets, err := loadTargets(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			if t.ID == DefaultTargetID || m.entries[t.ID] != nil {
+				continue
+			}
+			if err := m.addEntry(t); err != nil {
+				return nil, err
+			}
+		}
+		if len(targets) > 0 {
+			log.Printf("已加载%d个监控目标", len(targets))
+		}
+	}
+
+	return m, nil
+}
+
+// Start 启动定时任务
+func (m *Monitor) Start() {
+	m.cron.Start()
+}
+
+// configFor 根据共享参数生成目标的检测器配置
+func (m *Monitor) configFor(t Target) detector.Config {
+	config := m.base
+	config.TargetID = t.ID
+	config.Endpoint = t.Endpoint
+	config.APIKey = t.APIKey
+	config.Model = t.Model()
+	config.Interval = t.Interval
+	return config
+}
+
+// addEntry 为目标创建检测器并按间隔安排定时检测，调用方需持有写锁或处于初始化阶段
+func (m *Monitor) addEntry(t Target) error {
+	store, err := m.openStore(t.ID)
+	if err != nil {
+		return fmt.Errorf("打开监控目标%s的结果存储失败: %w", t.ID, err)
+	}
+
+	e := &entry{
+		target:   t,
+		detector: detector.NewDetectorWithStore(m.configFor(t), store),
+	}
+	m.entries[t.ID] = e
+	m.order = append(m.order, t.ID)
+	m.schedule(e)
+	return nil
+}
+
+// schedule 按目标的检测间隔重新安排定时任务
+func (m *Monitor) schedule(e *entry) {
+	if e.cronID != 0 {
+		m.cron.Remove(e.cronID)
+		e.cronID = 0
+	}
+	if e.target.Interval <= 0 {
+		return
+	}
+
+	id := e.target.ID
+	minutes := e.target.Interval
+	cronID, err := m.cron.AddFunc(fmt.Sprintf("@every %dm", minutes), func() {
+		log.Printf("执行定时检测任务[%s]，间隔%d分钟", id, minutes)
+		results, err := m.Detect(context.Background(), id)
+		if err != nil {
+			log.Printf("定时检测[%s]未执行: %v", id, err)
+			return
+		}
+		for _, result := range results {
+			log.Printf("定时检测完成[%s/%s]: 评分=%.1f, 结论=%s", id, result.Model, result.Score, result.Verdict)
+		}
+	})
+	if err != nil {
+		log.Printf("启动定时任务[%s]失败: %v", id, err)
+		return
+	}
+	e.cronID = cronID
+	log.Printf("定时检测[%s]已启动，间隔为%d分钟", id, minutes)
+}
+
+// persist 保存默认目标以外的监控目标，调用方需持有锁
+func (m *Monitor) persist() {
+	if m.path == "" {
+		return
+	}
+	var targets []Target
+	for _, id := range m.order {
+		if id != DefaultTargetID {
+			targets = append(targets, m.entries[id].target)
+		}
+	}
+	if err := saveTargets(m.path, targets); err != nil {
+		log.Printf("保存监控目标失败: %v", err)
+	}
+}
+
+// Targets 按添加顺序返回所有监控目标
+func (m *Monitor) Targets() []Target {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	targets := make([]Target, 0, len(m.order))
+	for _, id := range m.order {
+		targets = append(targets, m.entries[id].target)
+	}
+	return targets
+}
+
+// Target 按ID返回监控目标
+func (m *Monitor) Target(id string) (Target, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return Target{}, false
+	}
+	return e.target, true
+}
+
+// Detector 返回监控目标的检测器，不存在时返回nil
+func (m *Monitor) Detector(id string) *detector.Detector {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if e, ok := m.entries[id]; ok {
+		return e.detector
+	}
+	return nil
+}
+
+// Status 返回监控目标的当前状态
+func (m *Monitor) Status(id string) (Status, bool) {
+	m.mu.RLock()
+	e, ok := m.entries[id]
+	if !ok {
+		m.mu.RUnlock()
+		return Status{}, false
+	}
+	status := Status{Target: e.target, Detecting: e.detecting}
+	if e.detecting {
+		startedAt := e.startedAt
+		status.StartedAt = &startedAt
+	}
+	d := e.detector
+	m.mu.RUnlock()
+
+	if latest := d.GetLatestResult(); latest != nil {
+		summary := latest.WithoutRawResponse()
+		status.Latest = &summary
+	}
+	return status, true
+}
+
+// Statuses 按添加顺序返回所有监控目标的状态
+func (m *Monitor) Statuses() []Status {
+	var statuses []Status
+	for _, t := range m.Targets() {
+		if status, ok := m.Status(t.ID); ok {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// Add 添加一个监控目标并返回分配了ID的目标
+func (m *Monitor) Add(t Target) (Target, error) {
+	t = t.normalize()
+	if isRedacted(t.APIKey) {
+		return Target{}, errors.New("API密钥无效")
+	}
+	if err := t.validate(); err != nil {
+		return Target{}, err
+	}
+	id, err := newTargetID()
+	if err != nil {
+		return Target{}, err
+	}
+	t.ID = id
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.addEntry(t); err != nil {
+		return Target{}, err
+	}
+	m.persist()
+	return t, nil
+}
+
+// Update 更新监控目标；API密钥为空或为隐去形式时保留原密钥
+func (m *Monitor) Update(id string, t Target) (Target, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return Target{}, ErrNotFound
+	}
+
+	t = t.normalize()
+	t.ID = id
+	if t.APIKey == "" || isRedacted(t.APIKey) {
+		t.APIKey = e.target.APIKey
+	}
+	if err := t.validate(); err != nil {
+		return Target{}, err
+	}
+
+	m.apply(e, t)
+	m.persist()
+	return t, nil
+}
+
+// apply 将目标的变更应用到检测器和定时任务，调用方需持有写锁
+func (m *Monitor) apply(e *entry, t Target) {
+	oldInterval := e.target.Interval
+	e.target = t
+	e.detector.UpdateConfig(m.configFor(t))
+	if oldInterval != t.Interval {
+		m.schedule(e)
+	}
+}
+
+// Remove 删除监控目标，其历史结果文件会保留
+func (m *Monitor) Remove(id string) error {
+	if id == DefaultTargetID {
+		return ErrDefaultTarget
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if e.cronID != 0 {
+		m.cron.Remove(e.cronID)
+	}
+	delete(m.entries, id)
+	for i, oid := range m.order {
+		if oid == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	m.persist()
+	return nil
+}
+
+// SetInterval 修改监控目标的自动检测间隔，0表示停止自动检测
+func (m *Monitor) SetInterval(id string, minutes int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	t := e.target
+	t.Interval = minutes
+	m.apply(e, t)
+	m.persist()
+	return nil
+}
+
+// UpdateBase 更新共享的检测参数和默认监控目标（对应/api/config）
+//
+// API密钥为空或为隐去形式时保留原密钥。
+func (m *Monitor) UpdateBase(config detector.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	config.TargetID = ""
+	if config.APIKey = strings.TrimSpace(config.APIKey); config.APIKey == "" || isRedacted(config.APIKey) {
+		config.APIKey = m.entries[DefaultTargetID].target.APIKey
+	}
+	if config.MaxHistory <= 0 {
+		config.MaxHistory = m.base.MaxHistory
+	}
+	m.base = config
+
+	// 默认目标使用配置中的端点、密钥和模型，其余模型保持不变
+	e := m.entries[DefaultTargetID]
+	t := e.target
+	t.Endpoint = config.Endpoint
+	t.APIKey = config.APIKey
+	if len(t.Models) == 0 {
+		t.Models = []string{config.Model}
+	} else {
+		t.Models = append([]string{config.Model}, t.Models[1:]...)
+	}
+	t.Interval = config.Interval
+
+	// 共享参数变化后所有目标的检测器都需要更新
+	for _, id := range m.order {
+		if id == DefaultTargetID {
+			m.apply(e, t)
+			continue
+		}
+		other := m.entries[id]
+		other.detector.UpdateConfig(m.configFor(other.target))
+	}
+}
+
+// begin 将目标标记为检测中，返回检测器和检测时的目标配置
+func (m *Monitor) begin(id string) (*detector.Detector, Target, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return nil, Target{}, ErrNotFound
+	}
+	if e.detecting {
+		return nil, Target{}, ErrDetecting
+	}
+	e.detecting = true
+	e.startedAt = time.Now()
+	return e.detector, e.target, nil
+}
+
+// finish 清除目标的检测中标记（目标可能已被删除）
+func (m *Monitor) finish(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[id]; ok {
+		e.detecting = false
+	}
+}
+
+// Detect 使用目标的每个模型各执行一次检测并等待结果
+func (m *Monitor) Detect(ctx context.Context, id string) ([]detector.Result, error) {
+	d, t, err := m.begin(id)
+	if err != nil {
+		return nil, err
+	}
+	defer m.finish(id)
+	return sweep(ctx, d, t), nil
+}
+
+// DetectAsync 在后台使用目标的每个模型各执行一次检测
+func (m *Monitor) DetectAsync(id string) error {
+	d, t, err := m.begin(id)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer m.finish(id)
+		sweep(context.Background(), d, t)
+	}()
+	return nil
+}
+
+// sweep 依次检测目标的模型：配置的模型在前，自动发现的模型在后
+//
+// 自动发现的模型取自首个模型检测结果中models检测项记录的聊天模型，不再单独
+// 请求模型列表；只有没有配置模型时才先请求一次模型列表，确定首个检测的模型。
+func sweep(ctx context.Context, d *detector.Detector, t Target) []detector.Result {
+	models := append([]string(nil), t.Models...)
+	if !t.DiscoverModels {
+		return d.DetectModels(ctx, models)
+	}
+
+	if len(models) == 0 {
+		listed, err := listModels(ctx, d)
+		if err != nil || len(listed) == 0 {
+			log.Printf("监控目标[%s]没有配置模型，且未能从模型列表发现聊天模型: %v", t.ID, err)
+			return nil
+		}
+		models = listed[:1]
+	}
+
+	results := d.DetectModels(ctx, models[:1])
+	if len(results) == 0 {
+		return results
+	}
+	discovered := chatModels(results[0])
+	if len(discovered) == 0 {
+		log.Printf("监控目标[%s]的models检测项未记录聊天模型，本次不检测自动发现的模型", t.ID)
+	}
+	return append(results, d.DetectModels(ctx, mergeModels(t, models, discovered))...)
+}
+
+// mergeModels 返回首个模型之后需要检测的模型：其余配置的模型和最多maxDiscoveredModels个自动发现的模型
+func mergeModels(t Target, models, discovered []string) []string {
+	seen := make(map[string]bool)
+	for _, model := range models {
+		seen[model] = true
+	}
+	// 没有配置模型时首个模型本身来自自动发现
+	added := len(models) - len(t.Models)
+	rest := append([]string(nil), models[1:]...)
+	for _, model := range discovered {
+		if seen[model] {
+			continue
+		}
+		if added == maxDiscoveredModels {
+			log.Printf("监控目标[%s]发现的模型超过%d个，其余模型不检测", t.ID, maxDiscoveredModels)
+			break
+		}
+		rest = append(rest, model)
+		seen[model] = true
+		added++
+	}
+	return rest
+}
+
+// listModels 在单个检测项的超时时间内获取端点提供的聊天模型
+func listModels(ctx context.Context, d *detector.Detector) ([]string, error) {
+	// 端点无响应时不会阻塞整个检测
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(d.Config().CheckTimeout)*time.Second)
+	defer cancel()
+	return d.ListModels(ctx)
+}
+
+// chatModels 返回检测结果中models检测项记录的聊天模型
+//
+// 刚完成的检测中为[]string，从存储读取的结果经过JSON解码后为[]interface{}。
+func chatModels(r detector.Result) []string {
+	cr := r.Check("models")
+	if cr == nil {
+		return nil
+	}
+	switch v := cr.Data["chat_models"].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		models := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				models = append(models, s)
+			}
+		}
+		return models
+	}
+	return nil
+}
+
+// Matrix 返回监控目标每个模型的最新检测结果（不含原始响应）
+//
+// 配置的模型按配置顺序排在前面，其余曾检测过的模型（如自动发现的）按名称排在后面。
+func (m *Monitor) Matrix(id string) ([]detector.Result, error) {
+	m.mu.RLock()
+	e, ok := m.entries[id]
+	if !ok {
+		m.mu.RUnlock()
+		return nil, ErrNotFound
+	}
+	t := e.target
+	d := e.detector
+	m.mu.RUnlock()
+
+	latest := make(map[string]detector.Result)
+	for _, result := range d.GetResults() {
+		// 早期版本的结果没有记录模型
+		if result.Model != "" {
+			latest[result.Model] = result
+		}
+	}
+
+	var others []string
+	configured := make(map[string]bool)
+	for _, model := range t.Models {
+		configured[model] = true
+	}
+	for model := range latest {
+		if !configured[model] {
+			others = append(others, model)
+		}
+	}
+	sort.Strings(others)
+
+	var matrix []detector.Result
+	for _, model := range append(append([]string(nil), t.Models...), others...) {
+		if result, ok := latest[model]; ok {
+			matrix = append(matrix, result.WithoutRawResponse())
+		}
+	}
+	return matrix, nil
+}
+
+// FindResult 在所有监控目标中按ID查找检测结果
+func (m *Monitor) FindResult(id string) *detector.Result {
+	m.mu.RLock()
+	detectors := make([]*detector.Detector, 0, len(m.entries))
+	for _, e := range m.entries {
+		detectors = append(detectors, e.detector)
+	}
+	m.mu.RUnlock()
+
+	for _, d := range detectors {
+		if result := d.GetResult(id); result != nil {
+			return result
+		}
+	}
+	return nil
+}
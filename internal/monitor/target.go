@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/isGPTReal/internal/detector"
+)
+
+// DefaultTargetID 由命令行参数和/api/config管理的默认监控目标
+const DefaultTargetID = "default"
+
+// Target 表示一个被监控的API端点
+type Target struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Endpoint string   `json:"endpoint"`
+	APIKey   string   `json:"api_key"`
+	Models   []string `json:"models"`   // 检测使用的模型
+	Interval int      `json:"interval"` // 自动检测间隔（分钟），0表示不自动检测
+	Tags     []string `json:"tags"`
+	// DiscoverModels 为true时，将首个模型检测中/v1/models列出的聊天模型一并检测
+	DiscoverModels bool `json:"discover_models"`
+}
+
+// Model 返回目标的主模型
+func (t Target) Model() string {
+	if len(t.Models) == 0 {
+		return ""
+	}
+	return t.Models[0]
+}
+
+// Redacted 返回隐去API密钥的副本，用于接口返回
+func (t Target) Redacted() Target {
+	if t.APIKey != "" {
+		t.APIKey = detector.RedactKey(t.APIKey)
+	}
+	return t
+}
+
+// normalize 清理字段中的空白和空值
+func (t Target) normalize() Target {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Endpoint = strings.TrimSpace(t.Endpoint)
+	t.APIKey = strings.TrimSpace(t.APIKey)
+	t.Models = compact(t.Models)
+	t.Tags = compact(t.Tags)
+	if t.Name == "" {
+		t.Name = t.Endpoint
+	}
+	if t.Interval < 0 {
+		t.Interval = 0
+	}
+	return t
+}
+
+// validate 检查目标是否可用于检测
+func (t Target) validate() error {
+	switch {
+	case t.Endpoint == "":
+		return errors.New("必须提供API端点")
+	case t.APIKey == "":
+		return errors.New("必须提供API密钥")
+	case len(t.Models) == 0 && !t.DiscoverModels:
+		return errors.New("至少需要一个模型，或启用自动发现模型")
+	}
+	return nil
+}
+
+// compact 去掉字符串切片中的空白项
+func compact(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// isRedacted 判断提交的密钥是否为接口返回的隐去形式
+func isRedacted(key string) bool {
+	return strings.Contains(key, "***")
+}
+
+// newTargetID 生成监控目标的唯一ID
+func newTargetID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成目标ID失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// loadTargets 从JSON文件读取监控目标，文件不存在时返回空列表
+func loadTargets(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取监控目标失败: %w", err)
+	}
+
+	var targets []Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("解析监控目标失败: %w", err)
+	}
+	return targets, nil
+}
+
+// saveTargets 将监控目标写入JSON文件，先写临时文件再替换
+func saveTargets(path string, targets []Target) error {
+	data, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化监控目标失败: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("写入监控目标失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("写入监控目标失败: %w", err)
+	}
+	return nil
+}
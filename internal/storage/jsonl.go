@@ -0,0 +1,175 @@
+// Package storage 提供检测结果的持久化存储后端
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/user/isGPTReal/internal/detector"
+)
+
+// JSONLStore 以追加写入的JSONL文件保存检测结果，每行一条结果
+//
+// 打开时将文件中的结果全部加载到内存，读取直接走内存；保存时追加一行。
+// 清理过期结果时立即从内存中删除，但只有文件中的过期行积累到一定数量后
+// 才重写整个文件，避免达到保留上限后每次保存都重写包含原始请求记录的文件。
+type JSONLStore struct {
+	mu      sync.RWMutex
+	path    string
+	results []detector.Result
+	lines   int // 文件中的行数，包括已从内存中清理的过期结果
+}
+
+// maxLineSize 单条结果的最大字节数，原始请求记录可能较大
+const maxLineSize = 64 * 1024 * 1024
+
+// minCompactSlack 文件中至少积累这么多过期行才重写文件；
+// 保留数量较多时按其四分之一计算
+const minCompactSlack = 20
+
+// NewJSONLStore 打开（不存在时创建）指定路径的JSONL存储
+func NewJSONLStore(path string) (*JSONLStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %w", err)
+	}
+
+	s := &JSONLStore{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load 从文件加载所有结果，无法解析的行会被跳过
+func (s *JSONLStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("打开结果文件失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		s.lines++
+		var result detector.Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			log.Printf("跳过结果文件%s第%d行: %v", s.path, line, err)
+			continue
+		}
+		s.results = append(s.results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取结果文件失败: %w", err)
+	}
+	return nil
+}
+
+// Save 追加保存一条检测结果
+func (s *JSONLStore) Save(result detector.Result) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("序列化检测结果失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开结果文件失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入结果文件失败: %w", err)
+	}
+	s.results = append(s.results, result)
+	s.lines++
+	return nil
+}
+
+// List 返回所有检测结果
+func (s *JSONLStore) List() ([]detector.Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]detector.Result(nil), s.results...), nil
+}
+
+// Get 按ID返回检测结果，不存在时返回nil
+func (s *JSONLStore) Get(id string) (*detector.Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.results {
+		if s.results[i].ID == id {
+			result := s.results[i]
+			return &result, nil
+		}
+	}
+	return nil, nil
+}
+
+// Prune 按保留策略删除过期的结果，文件中的过期行积累足够多时重写文件
+func (s *JSONLStore) Prune(retention detector.Retention) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := retention.Apply(s.results, time.Now())
+	removed := len(s.results) - len(kept)
+	if removed > 0 {
+		s.results = append([]detector.Result(nil), kept...)
+	}
+
+	slack := max(minCompactSlack, retention.MaxCount/4)
+	if s.lines-len(s.results) <= slack {
+		return removed, nil
+	}
+	if err := s.rewrite(s.results); err != nil {
+		return removed, err
+	}
+	s.lines = len(s.results)
+	return removed, nil
+}
+
+// rewrite 先写入临时文件再替换，避免写入中断导致数据损坏
+func (s *JSONLStore) rewrite(results []detector.Result) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			tmp.Close()
+			return fmt.Errorf("序列化检测结果失败: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("替换结果文件失败: %w", err)
+	}
+	return nil
+}
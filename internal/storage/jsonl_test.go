@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/user/isGPTReal/internal/detector"
+)
+
+// fileLines 返回文件中的行数
+func fileLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestJSONLStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s, err := NewJSONLStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := s.Save(detector.Result{ID: fmt.Sprintf("r%d", i), Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 无法解析的行在加载时被跳过
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{not json\n")
+	f.Close()
+
+	reopened, err := NewJSONLStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _ := reopened.List()
+	if len(results) != 3 || results[0].ID != "r1" || results[2].ID != "r3" {
+		t.Fatalf("重新打开后的结果 = %v, want r1~r3", results)
+	}
+	if r, _ := reopened.Get("r2"); r == nil {
+		t.Error("Get(r2) = nil")
+	}
+}
+
+func TestJSONLStorePruneCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s, err := NewJSONLStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retention := detector.Retention{MaxCount: 5}
+
+	// 每次保存后清理，模拟检测器的行为
+	save := func(i int) {
+		t.Helper()
+		if err := s.Save(detector.Result{ID: fmt.Sprintf("r%d", i), Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Prune(retention); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 过期行不超过minCompactSlack时只从内存中删除，不重写文件
+	total := retention.MaxCount + minCompactSlack
+	for i := 1; i <= total; i++ {
+		save(i)
+	}
+	results, _ := s.List()
+	if len(results) != retention.MaxCount || results[0].ID != fmt.Sprintf("r%d", total-retention.MaxCount+1) {
+		t.Fatalf("清理后的结果 = %v, want 最新的%d条", results, retention.MaxCount)
+	}
+	if got := fileLines(t, path); got != total {
+		t.Fatalf("文件行数 = %d, want %d（尚未重写）", got, total)
+	}
+
+	// 再保存一条后过期行超过minCompactSlack，重写文件只保留未过期的结果
+	save(total + 1)
+	if got := fileLines(t, path); got != retention.MaxCount {
+		t.Fatalf("文件行数 = %d, want %d（已重写）", got, retention.MaxCount)
+	}
+
+	reopened, err := NewJSONLStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _ = reopened.List()
+	if len(results) != retention.MaxCount || results[len(results)-1].ID != fmt.Sprintf("r%d", total+1) {
+		t.Errorf("重新打开后的结果 = %v, want 最新的%d条", results, retention.MaxCount)
+	}
+}
+
+func TestJSONLStoreCompactSlackScalesWithMaxCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s, err := NewJSONLStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 保留数量较多时按其四分之一计算允许的过期行数
+	retention := detector.Retention{MaxCount: 200}
+	for i := 1; i <= retention.MaxCount+minCompactSlack+1; i++ {
+		if err := s.Save(detector.Result{ID: fmt.Sprintf("r%d", i), Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Prune(retention); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fileLines(t, path), retention.MaxCount+minCompactSlack+1; got != want {
+		t.Errorf("文件行数 = %d, want %d（过期行未超过MaxCount/4，不应重写）", got, want)
+	}
+}